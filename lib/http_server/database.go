@@ -0,0 +1,48 @@
+package http_server
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/betam/glb/lib/sql"
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/try"
+)
+
+// DatabaseError maps classified database errors to http errors: not found to 404, unique and foreign key
+// violations to 409 with the violated column (or constraint) as the field, stale versions to 409. Other errors
+// are returned as is.
+func DatabaseError(err error) error {
+	if errors.Is(err, query.ErrStaleVersion) {
+		return NewError(fasthttp.StatusConflict, query.ErrStaleVersion.Error())
+	}
+	var classified *sql.Error
+	if !errors.As(err, &classified) {
+		return err
+	}
+	field := classified.Column
+	if field == "" {
+		field = classified.Constraint
+	}
+	switch classified.Kind {
+	case sql.ErrNotFound:
+		return NewError(fasthttp.StatusNotFound, classified.Kind.Error())
+	case sql.ErrUniqueViolation, sql.ErrForeignKey:
+		return NewFieldError(fasthttp.StatusConflict, field, classified.Kind.Error())
+	}
+	return err
+}
+
+// DatabaseErrors is a middleware applying DatabaseError to errors thrown by the handler.
+func DatabaseErrors(ctx *fasthttp.RequestCtx, next func(ctx *fasthttp.RequestCtx) Response) (response Response) {
+	try.Catch(
+		func() {
+			response = next(ctx)
+		},
+		func(throwable error) {
+			panic(DatabaseError(throwable))
+		},
+	)
+	return response
+}
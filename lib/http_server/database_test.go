@@ -0,0 +1,36 @@
+package http_server
+
+import (
+	dbsql "database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+
+	"github.com/betam/glb/lib/sql"
+	"github.com/betam/glb/lib/sql/query"
+)
+
+func TestDatabaseError(t *testing.T) {
+	assert.Equal(t, NewError(fasthttp.StatusNotFound, "record not found"), DatabaseError(sql.Classify(dbsql.ErrNoRows)))
+	assert.Equal(
+		t,
+		NewFieldError(fasthttp.StatusConflict, "users_email_key", "unique violation"),
+		DatabaseError(&sql.Error{Kind: sql.ErrUniqueViolation, Constraint: "users_email_key", Err: fmt.Errorf("duplicate")}),
+	)
+	assert.Equal(
+		t,
+		NewError(fasthttp.StatusConflict, "stale version"),
+		DatabaseError(fmt.Errorf("%w: users.version is not 3", query.ErrStaleVersion)),
+	)
+	other := fmt.Errorf("something went wrong")
+	assert.Same(t, other, DatabaseError(other))
+
+	ctx := &fasthttp.RequestCtx{}
+	Handler(func(ctx *fasthttp.RequestCtx) Response {
+		panic(sql.Classify(dbsql.ErrNoRows))
+	}, DatabaseErrors)(ctx)
+	assert.Equal(t, 404, ctx.Response.StatusCode())
+	assert.Equal(t, `{"code":404,"message":"record not found"}`, string(ctx.Response.Body()))
+}
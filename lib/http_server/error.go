@@ -1,12 +1,17 @@
 package http_server
 
 func NewError(code int, message string) Error {
-	return Error{message, code}
+	return Error{message: message, Code: code}
+}
+
+func NewFieldError(code int, field string, message string) Error {
+	return Error{message: message, Code: code, Field: field}
 }
 
 type Error struct {
 	message string
 	Code    int
+	Field   string
 }
 
 func (e Error) Error() string {
@@ -0,0 +1,27 @@
+package http_server
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/try"
+)
+
+func ParseFilter(body []byte, builder query.Builder, schema query.FilterSchema) query.Builder {
+	try.Catch(
+		func() {
+			builder = query.ParseFilter(body, builder, schema)
+		},
+		func(throwable error) {
+			var filterError query.FilterError
+			if errors.As(throwable, &filterError) {
+				panic(NewFieldError(fasthttp.StatusBadRequest, filterError.Path, throwable.Error()))
+			}
+			panic(NewError(fasthttp.StatusBadRequest, throwable.Error()))
+		},
+	)
+
+	return builder
+}
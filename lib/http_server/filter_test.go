@@ -0,0 +1,44 @@
+package http_server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+
+	"github.com/betam/glb/lib/sql/query"
+)
+
+func TestParseFilter(t *testing.T) {
+	schema := query.FilterSchema{Fields: map[string]query.FilterField{"name": {Type: query.FilterString}}}
+
+	t.Run(
+		"Success",
+		func(t *testing.T) {
+			q, params := ParseFilter([]byte(`{"filter":{"conditions":[["name","eq","a"]]}}`), query.NewBuilder("test"), schema).Build()
+			assert.Equal(t, "select * from test where (name = $1) order by id asc", q)
+			assert.Equal(t, &[]any{"a"}, params)
+		},
+	)
+
+	t.Run(
+		"Error",
+		func(t *testing.T) {
+			assert.PanicsWithValue(
+				t,
+				NewFieldError(fasthttp.StatusBadRequest, "filter.conditions[0][0]", "invalid filter: filter.conditions[0][0]: field 'age' is not allowed"),
+				func() {
+					ParseFilter([]byte(`{"filter":{"conditions":[["age","eq",1]]}}`), query.NewBuilder("test"), schema)
+				},
+			)
+
+			ctx := &fasthttp.RequestCtx{}
+			Handler(func(ctx *fasthttp.RequestCtx) Response {
+				ParseFilter([]byte(`{"filter":{"conditions":[["age","eq",1]]}}`), query.NewBuilder("test"), schema)
+				return nil
+			})(ctx)
+			assert.Equal(t, 400, ctx.Response.StatusCode())
+			assert.Equal(t, `{"code":400,"field":"filter.conditions[0][0]","message":"invalid filter: filter.conditions[0][0]: field 'age' is not allowed"}`, string(ctx.Response.Body()))
+		},
+	)
+}
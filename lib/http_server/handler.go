@@ -38,9 +38,11 @@ func Handler(handler func(ctx *fasthttp.RequestCtx) Response, middlewares ...Mid
 			},
 			func(throwable error) {
 				code := 500
+				field := ""
 				isNotHttpError := true
 				if err, ok := throwable.(Error); ok {
 					code = err.Code
+					field = err.Field
 					isNotHttpError = false
 				}
 				ctx.SetStatusCode(code)
@@ -49,6 +51,9 @@ func Handler(handler func(ctx *fasthttp.RequestCtx) Response, middlewares ...Mid
 					"message": throwable.Error(),
 					"code":    code,
 				}
+				if field != "" {
+					message["field"] = field
+				}
 				ctx.SetBody(try.Throw(json.Marshal(message)))
 				logrus.Infof("%s %s [%d]", ctx.Method(), ctx.Path(), code)
 				logrus.Tracef(string(ctx.PostBody()))
@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Array maps a one-dimensional PostgreSQL array to a slice. A NULL array scans into a nil slice and NULL
+// elements into the zero value of T.
+type Array[T any] []T
+
+func NewArray[T any](values ...T) Array[T] {
+	return values
+}
+
+func (a Array[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	items := make([]string, 0, len(a))
+	for _, item := range a {
+		value, err := convert(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, arrayElement(value))
+	}
+	return "{" + strings.Join(items, ",") + "}", nil
+}
+
+func arrayElement(value driver.Value) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case int64, float64:
+		return fmt.Sprintf("%v", v)
+	case time.Time:
+		return `"` + v.Format(time.RFC3339Nano) + `"`
+	case []byte:
+		return quoteArrayElement(string(v))
+	default:
+		return quoteArrayElement(fmt.Sprintf("%v", v))
+	}
+}
+
+func quoteArrayElement(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func (a *Array[T]) Scan(value any) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, a)
+	}
+
+	elements, err := parseArray(text)
+	if err != nil {
+		return err
+	}
+	result := make(Array[T], len(elements))
+	for idx, element := range elements {
+		var src any
+		if element != nil {
+			src = *element
+		}
+		if err = assign(&result[idx], src); err != nil {
+			return err
+		}
+	}
+	*a = result
+	return nil
+}
+
+// parseArray splits the text representation of a one-dimensional array; unquoted NULL elements are returned as nil.
+func parseArray(text string) ([]*string, error) {
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return nil, fmt.Errorf("invalid array '%s'", text)
+	}
+	body := text[1 : len(text)-1]
+	if body == "" {
+		return []*string{}, nil
+	}
+
+	var elements []*string
+	for idx := 0; idx <= len(body); {
+		var element strings.Builder
+		quoted := idx < len(body) && body[idx] == '"'
+		if quoted {
+			idx++
+			for ; idx < len(body) && body[idx] != '"'; idx++ {
+				if body[idx] == '\\' && idx+1 < len(body) {
+					idx++
+				}
+				element.WriteByte(body[idx])
+			}
+			if idx >= len(body) {
+				return nil, fmt.Errorf("invalid array '%s': unterminated quote", text)
+			}
+			idx++
+		} else {
+			for ; idx < len(body) && body[idx] != ','; idx++ {
+				if body[idx] == '{' {
+					return nil, fmt.Errorf("invalid array '%s': multidimensional arrays are not supported", text)
+				}
+				element.WriteByte(body[idx])
+			}
+		}
+		if idx < len(body) && body[idx] != ',' {
+			return nil, fmt.Errorf("invalid array '%s': unexpected character at %d", text, idx+1)
+		}
+		value := element.String()
+		if !quoted && strings.EqualFold(value, "null") {
+			elements = append(elements, nil)
+		} else {
+			elements = append(elements, &value)
+		}
+		idx++
+	}
+	return elements, nil
+}
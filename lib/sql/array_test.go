@@ -0,0 +1,78 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArray(t *testing.T) {
+	t.Run(
+		"Value",
+		func(t *testing.T) {
+			value, err := NewArray("a", `b "c"`, `d\e`).Value()
+			assert.Nil(t, err)
+			assert.Equal(t, `{"a","b \"c\"","d\\e"}`, value)
+
+			value, err = NewArray(1, 2, 3).Value()
+			assert.Nil(t, err)
+			assert.Equal(t, "{1,2,3}", value)
+
+			value, err = NewArray(true, false).Value()
+			assert.Nil(t, err)
+			assert.Equal(t, "{t,f}", value)
+
+			value, err = Array[int](nil).Value()
+			assert.Nil(t, err)
+			assert.Nil(t, value)
+		},
+	)
+
+	t.Run(
+		"Scan",
+		func(t *testing.T) {
+			var strings Array[string]
+			assert.Nil(t, strings.Scan([]byte(`{a,"b \"c\"","d\\e",NULL,"NULL"}`)))
+			assert.Equal(t, Array[string]{"a", `b "c"`, `d\e`, "", "NULL"}, strings)
+
+			var numbers Array[int64]
+			assert.Nil(t, numbers.Scan("{1,-2,3}"))
+			assert.Equal(t, Array[int64]{1, -2, 3}, numbers)
+
+			var flags Array[bool]
+			assert.Nil(t, flags.Scan("{t,f}"))
+			assert.Equal(t, Array[bool]{true, false}, flags)
+
+			var times Array[time.Time]
+			assert.Nil(t, times.Scan(`{"2024-01-02 03:04:05+00","2024-01-02 03:04:05.123456+05:30","2024-01-02 03:04:05","2024-01-02T03:04:05Z"}`))
+			assert.Len(t, times, 4)
+			assert.True(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Equal(times[0]))
+			assert.True(t, time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.FixedZone("", 5*3600+30*60)).Equal(times[1]))
+			assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), times[2])
+			assert.True(t, times[0].Equal(times[3]))
+
+			var dates Array[time.Time]
+			assert.Nil(t, dates.Scan("{2024-01-02}"))
+			assert.Equal(t, Array[time.Time]{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, dates)
+
+			var empty Array[int]
+			assert.Nil(t, empty.Scan("{}"))
+			assert.Equal(t, Array[int]{}, empty)
+			assert.Nil(t, empty.Scan(nil))
+			assert.Nil(t, empty)
+		},
+	)
+
+	t.Run(
+		"Malformed",
+		func(t *testing.T) {
+			var numbers Array[int]
+			assert.EqualError(t, numbers.Scan("1,2"), "invalid array '1,2'")
+			assert.EqualError(t, numbers.Scan(`{"1}`), `invalid array '{"1}': unterminated quote`)
+			assert.EqualError(t, numbers.Scan("{{1},{2}}"), "invalid array '{{1},{2}}': multidimensional arrays are not supported")
+			assert.EqualError(t, numbers.Scan("{a}"), `cannot parse 'a' into int: strconv.ParseInt: parsing "a": invalid syntax`)
+			assert.EqualError(t, numbers.Scan(1), "cannot scan int into *sql.Array[int]")
+		},
+	)
+}
@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/betam/glb/lib/list"
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/try"
+)
+
+// MaxParameters is the PostgreSQL limit of bind parameters in a single statement.
+const MaxParameters = 65535
+
+var copyDrivers = []string{"postgres"}
+
+func NewBulkWriter(connection Db, table string, fields ...string) *bulkWriter {
+	return &bulkWriter{
+		connection: connection,
+		table:      table,
+		fields:     fields,
+	}
+}
+
+type bulkWriter struct {
+	connection     Db
+	table          string
+	fields         []string
+	chunk          int
+	copy           bool
+	conflictKey    string
+	conflictFields []string
+}
+
+func (w *bulkWriter) Chunk(rows int) *bulkWriter {
+	w.chunk = rows
+	return w
+}
+
+func (w *bulkWriter) Copy(enabled bool) *bulkWriter {
+	w.copy = enabled
+	return w
+}
+
+func (w *bulkWriter) Conflict(conflict string, excluded ...string) *bulkWriter {
+	w.conflictKey = conflict
+	w.conflictFields = excluded
+	return w
+}
+
+func (w *bulkWriter) Write(ctx context.Context, values ...any) int {
+	if len(values) == 0 {
+		return 0
+	}
+	fields, rows := query.Rows(w.fields, values...)
+	if len(rows) == 0 {
+		return 0
+	}
+	// Scope columns are part of the row before the copy or chunk sizes are chosen.
+	fields, rows = query.ScopeRows(ctx, w.table, fields, rows)
+
+	ctx, tx, commit, rollback := NewContextWithTransaction(ctx, w.connection)
+	defer rollback()
+
+	var affected int
+	if w.copy && w.conflictKey == "" && supportsCopy(tx) && !hasRaw(rows) {
+		affected = w.copyIn(ctx, tx, fields, rows)
+	} else {
+		for _, chunk := range w.chunks(fields, rows) {
+			builder := query.NewBuilder(w.table).Insert(fields...).Values(chunk...)
+			if w.conflictKey != "" {
+				builder.Conflict(w.conflictKey, w.conflictFields...)
+			}
+			affected += query.Exec(ctx, tx.ExecContext, builder)
+		}
+	}
+
+	commit()
+	return affected
+}
+
+func (w *bulkWriter) chunks(fields []string, rows [][]any) [][]any {
+	size := len(rows)
+	if len(fields) > 0 && MaxParameters/len(fields) < size {
+		size = MaxParameters / len(fields)
+	}
+	if w.chunk > 0 && w.chunk < size {
+		size = w.chunk
+	}
+
+	var chunks [][]any
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk := make([]any, 0, end-start)
+		for _, row := range rows[start:end] {
+			chunk = append(chunk, row)
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func (w *bulkWriter) copyIn(ctx context.Context, tx *sqlx.Tx, fields []string, rows [][]any) int {
+	statement := try.Throw(tx.PrepareContext(ctx, fmt.Sprintf("copy %s (%s) from stdin", w.table, strings.Join(fields, ", "))))
+	defer func() { _ = statement.Close() }()
+
+	for _, row := range rows {
+		_ = try.Throw(statement.ExecContext(ctx, row...))
+	}
+	_ = try.Throw(statement.ExecContext(ctx))
+	return len(rows)
+}
+
+func supportsCopy(tx *sqlx.Tx) bool {
+	return list.Contains(tx.DriverName(), copyDrivers)
+}
+
+func hasRaw(rows [][]any) bool {
+	for _, row := range rows {
+		for _, value := range row {
+			if query.IsRaw(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+func TestBulkWriterChunks(t *testing.T) {
+	rows := make([][]any, 0, 7)
+	for i := 0; i < 7; i++ {
+		rows = append(rows, []any{i, i})
+	}
+
+	chunks := NewBulkWriter(nil, "test", "a", "b").Chunk(3).chunks([]string{"a", "b"}, rows)
+	assert.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], 3)
+	assert.Len(t, chunks[2], 1)
+	assert.Equal(t, []any{6, 6}, chunks[2][0])
+
+	wide := make([]string, 30000)
+	chunks = NewBulkWriter(nil, "test").chunks(wide, rows)
+	assert.Len(t, chunks, 4)
+	assert.Len(t, chunks[0], 2)
+}
+
+func TestBulkWriterWrite(t *testing.T) {
+	db := sqltest.New()
+	db.On("^insert").Affected(2)
+
+	affected := NewBulkWriter(db, "test", "a", "b").Chunk(2).Write(context.Background(), []any{1, 2}, []any{3, 4}, []any{5, 6})
+	assert.Equal(t, 4, affected)
+	assert.Equal(t, []string{"insert into test (a, b) values ($1, $2), ($3, $4)", "insert into test (a, b) values ($1, $2)"}, db.Queries())
+	assert.Equal(t, []string{"begin", "commit"}, db.Transactions())
+}
+
+func TestBulkWriterScopes(t *testing.T) {
+	query.SetScopes(query.Tenant("tenant_id", "test"))
+	defer query.SetScopes()
+	ctx := query.NewContextWithTenant(context.Background(), "t1")
+
+	t.Run("insert", func(t *testing.T) {
+		db := sqltest.New()
+		db.On("^insert").Affected(1)
+
+		NewBulkWriter(db, "test", "a", "b").Write(ctx, []any{1, 2})
+		assert.Equal(t, []string{"insert into test (a, b, tenant_id) values ($1, $2, $3)"}, db.Queries())
+		assert.Equal(t, []any{int64(1), int64(2), "t1"}, db.Statements()[0].Args)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		copyDrivers = append(copyDrivers, sqltest.DriverName)
+		defer func() { copyDrivers = copyDrivers[:len(copyDrivers)-1] }()
+		db := sqltest.New()
+		db.On("^copy").Affected(1)
+
+		affected := NewBulkWriter(db, "test", "a", "b").Copy(true).Write(ctx, []any{1, 2}, []any{3, 4})
+		assert.Equal(t, 2, affected)
+		assert.Equal(t, "copy test (a, b, tenant_id) from stdin", db.Queries()[0])
+		assert.Equal(t, []any{int64(1), int64(2), "t1"}, db.Statements()[0].Args)
+		assert.Equal(t, []any{int64(3), int64(4), "t1"}, db.Statements()[1].Args)
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		db := sqltest.New()
+		db.On("^insert").Affected(1)
+
+		// two rows of 32767 columns fit the parameter limit, but not with the tenant column
+		wide := make([]string, 32767)
+		for i := range wide {
+			wide[i] = fmt.Sprintf("c%d", i)
+		}
+		NewBulkWriter(db, "test", wide...).Write(ctx, make([]any, len(wide)), make([]any, len(wide)))
+		assert.Len(t, db.Queries(), 2)
+	})
+}
@@ -0,0 +1,103 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// timeLayouts are the text forms of timestamptz, timestamp and date in the PostgreSQL output, followed by RFC 3339.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07:00:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+// assign stores a driver value into dest, converting between compatible kinds and parsing textual values
+// the way database/sql does for scan targets.
+func assign(dest any, src any) error {
+	if scanner, ok := dest.(dbsql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	target := reflect.ValueOf(dest).Elem()
+	if src == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	if bytes, ok := src.([]byte); ok {
+		if target.Kind() == reflect.Slice && target.Type().Elem().Kind() == reflect.Uint8 {
+			target.SetBytes(append([]byte{}, bytes...))
+			return nil
+		}
+		src = string(bytes)
+	}
+
+	value := reflect.ValueOf(src)
+	if text, ok := src.(string); ok && target.Kind() != reflect.String {
+		return parse(target, text)
+	}
+	if value.Type().ConvertibleTo(target.Type()) && (target.Kind() != reflect.String || value.Kind() == reflect.String) {
+		target.Set(value.Convert(target.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %s", src, target.Type())
+}
+
+func parse(target reflect.Value, text string) error {
+	var err error
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var v int64
+		v, err = strconv.ParseInt(text, 10, target.Type().Bits())
+		target.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var v uint64
+		v, err = strconv.ParseUint(text, 10, target.Type().Bits())
+		target.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		var v float64
+		v, err = strconv.ParseFloat(text, target.Type().Bits())
+		target.SetFloat(v)
+	case reflect.Bool:
+		var v bool
+		v, err = strconv.ParseBool(text)
+		target.SetBool(v)
+	default:
+		if target.Type() == reflect.TypeOf(time.Time{}) {
+			var v time.Time
+			v, err = parseTime(text)
+			target.Set(reflect.ValueOf(v))
+			break
+		}
+		return fmt.Errorf("cannot parse '%s' into %s", text, target.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("cannot parse '%s' into %s: %w", text, target.Type(), err)
+	}
+	return nil
+}
+
+func parseTime(text string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var v time.Time
+		if v, err = time.Parse(layout, text); err == nil {
+			return v, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// convert turns a value into a driver value, honoring driver.Valuer implementations.
+func convert(value any) (driver.Value, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(value)
+}
@@ -1,40 +1,102 @@
 package sql
 
 import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
 
 	"github.com/betam/glb/lib/try"
 )
 
+const (
+	defaultReconnectBackoff    = 100 * time.Millisecond
+	defaultMaxReconnectBackoff = 30 * time.Second
+)
+
 type Db interface {
 	Connect() *sqlx.DB
 	Timeout() time.Duration
 }
 
+type PoolOptions struct {
+	MaxOpen             int
+	MaxIdle             int
+	MaxLifetime         time.Duration
+	MaxIdleTime         time.Duration
+	HealthInterval      time.Duration
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+}
+
+type Stats struct {
+	dbsql.DBStats
+	Healthy    bool
+	Reconnects int
+	LastError  error
+}
+
 func NewDb(
 	driver string,
 	dsn string,
 	timeout time.Duration,
+	pool ...PoolOptions,
 ) *db {
-	return &db{
+	c := &db{
 		driver:  driver,
 		dsn:     dsn,
 		timeout: timeout,
+		healthy: true,
 	}
+	if len(pool) == 1 {
+		c.pool = pool[0]
+	} else if len(pool) > 1 {
+		panic(fmt.Errorf("unexpected argument count: expected 0 or 1 pool options given %d", len(pool)))
+	}
+	if c.pool.ReconnectBackoff <= 0 {
+		c.pool.ReconnectBackoff = defaultReconnectBackoff
+	}
+	if c.pool.MaxReconnectBackoff <= 0 {
+		c.pool.MaxReconnectBackoff = defaultMaxReconnectBackoff
+	}
+	return c
 }
 
 type db struct {
-	driver  string
-	dsn     string
-	timeout time.Duration
-	db      *sqlx.DB
+	driver     string
+	dsn        string
+	timeout    time.Duration
+	pool       PoolOptions
+	mutex      sync.RWMutex
+	db         *sqlx.DB
+	healthy    bool
+	reconnects int
+	lastError  error
+	stop       context.CancelFunc
+	onConnect  []func(*sqlx.DB)
 }
 
 func (c *db) Connect() *sqlx.DB {
-	if c.db == nil || c.db.Ping() != nil {
-		c.db = try.Throw(sqlx.Connect(c.driver, c.dsn))
+	c.mutex.RLock()
+	connection := c.db
+	c.mutex.RUnlock()
+	if connection != nil {
+		return connection
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.db == nil {
+		c.db = c.open(context.Background(), c.onConnect)
+		if c.pool.HealthInterval > 0 {
+			ctx, cancel := context.WithCancel(context.Background())
+			c.stop = cancel
+			go c.watch(ctx)
+		}
 	}
 	return c.db
 }
@@ -44,8 +106,140 @@ func (c *db) Timeout() time.Duration {
 }
 
 func (c *db) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stop != nil {
+		c.stop()
+		c.stop = nil
+	}
 	if c.db != nil {
-		return c.db.Close()
+		err := c.db.Close()
+		c.db = nil
+		return err
 	}
 	return nil
 }
+
+func (c *db) Healthy() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.healthy
+}
+
+func (c *db) Stats() Stats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	stats := Stats{Healthy: c.healthy, Reconnects: c.reconnects, LastError: c.lastError}
+	if c.db != nil {
+		stats.DBStats = c.db.Stats()
+	}
+	return stats
+}
+
+// OnConnect registers a callback invoked with every new pool, including pools created by a reconnect.
+func (c *db) OnConnect(callback func(*sqlx.DB)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onConnect = append(c.onConnect, callback)
+}
+
+func (c *db) open(ctx context.Context, callbacks []func(*sqlx.DB)) *sqlx.DB {
+	connection := try.Throw(sqlx.ConnectContext(ctx, c.driver, c.dsn))
+	if c.pool.MaxOpen > 0 {
+		connection.SetMaxOpenConns(c.pool.MaxOpen)
+	}
+	if c.pool.MaxIdle > 0 {
+		connection.SetMaxIdleConns(c.pool.MaxIdle)
+	}
+	if c.pool.MaxLifetime > 0 {
+		connection.SetConnMaxLifetime(c.pool.MaxLifetime)
+	}
+	if c.pool.MaxIdleTime > 0 {
+		connection.SetConnMaxIdleTime(c.pool.MaxIdleTime)
+	}
+	for _, callback := range callbacks {
+		callback(connection)
+	}
+	return connection
+}
+
+func (c *db) watch(ctx context.Context) {
+	ticker := time.NewTicker(c.pool.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check(ctx)
+		}
+	}
+}
+
+func (c *db) check(ctx context.Context) {
+	c.mutex.RLock()
+	connection, callbacks := c.db, c.onConnect
+	c.mutex.RUnlock()
+	if connection == nil {
+		return
+	}
+
+	err := c.ping(ctx, connection)
+	c.mutex.Lock()
+	c.healthy = err == nil
+	if err != nil {
+		c.lastError = err
+	}
+	c.mutex.Unlock()
+	if err == nil {
+		return
+	}
+
+	logrus.Warnf("database is unhealthy, reconnecting: %v", err)
+	backoff := c.pool.ReconnectBackoff
+	for {
+		var fresh *sqlx.DB
+		try.Catch(
+			func() { fresh = c.open(ctx, callbacks) },
+			func(throwable error) { err = throwable },
+		)
+		if fresh != nil {
+			c.mutex.Lock()
+			if c.db == nil {
+				c.mutex.Unlock()
+				_ = fresh.Close()
+				return
+			}
+			old := c.db
+			c.db = fresh
+			c.healthy = true
+			c.reconnects++
+			c.mutex.Unlock()
+			_ = old.Close()
+			logrus.Infof("database reconnected")
+			return
+		}
+
+		c.mutex.Lock()
+		c.lastError = err
+		c.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > c.pool.MaxReconnectBackoff {
+			backoff = c.pool.MaxReconnectBackoff
+		}
+	}
+}
+
+func (c *db) ping(ctx context.Context, connection *sqlx.DB) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	return connection.PingContext(ctx)
+}
@@ -0,0 +1,78 @@
+package sql
+
+import (
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var pingDriverDown atomic.Bool
+
+type pingDriver struct{}
+
+func (pingDriver) Open(string) (driver.Conn, error) {
+	if pingDriverDown.Load() {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return &pingConn{}, nil
+}
+
+type pingConn struct{}
+
+func (*pingConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (*pingConn) Close() error                        { return nil }
+func (*pingConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf("not supported") }
+
+func init() {
+	dbsql.Register("glb-ping", pingDriver{})
+}
+
+func TestDb(t *testing.T) {
+	t.Run(
+		"Pool",
+		func(t *testing.T) {
+			connection := NewDb("glb-ping", "", time.Second, PoolOptions{MaxOpen: 3, MaxIdle: 1})
+			defer func() { _ = connection.Close() }()
+
+			first := connection.Connect()
+			assert.Same(t, first, connection.Connect())
+			assert.Equal(t, 3, connection.Stats().MaxOpenConnections)
+			assert.True(t, connection.Stats().Healthy)
+		},
+	)
+
+	t.Run(
+		"Reconnect",
+		func(t *testing.T) {
+			connection := NewDb("glb-ping", "", time.Second, PoolOptions{HealthInterval: 10 * time.Millisecond, ReconnectBackoff: 5 * time.Millisecond})
+			defer func() { _ = connection.Close() }()
+
+			var connects atomic.Int32
+			connection.OnConnect(func(*sqlx.DB) { connects.Add(1) })
+			first := connection.Connect()
+
+			pingDriverDown.Store(true)
+			_ = first.Close()
+			assert.Eventually(t, func() bool { return !connection.Healthy() }, time.Second, 5*time.Millisecond)
+			assert.Error(t, connection.Stats().LastError)
+
+			pingDriverDown.Store(false)
+			assert.Eventually(t, func() bool { return connection.Healthy() && connection.Stats().Reconnects == 1 }, time.Second, 5*time.Millisecond)
+			assert.NotSame(t, first, connection.Connect())
+			assert.Equal(t, int32(2), connects.Load())
+		},
+	)
+
+	t.Run(
+		"WrongArguments",
+		func(t *testing.T) {
+			assert.PanicsWithError(t, "unexpected argument count: expected 0 or 1 pool options given 2", func() { NewDb("glb-ping", "", 0, PoolOptions{}, PoolOptions{}) })
+		},
+	)
+}
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/betam/glb/lib/list"
+)
+
+var ErrInvalidEnum = fmt.Errorf("invalid enum value")
+
+// EnumValues is implemented by string types listing their allowed values.
+type EnumValues[T any] interface {
+	~string
+	Values() []T
+}
+
+// Enum restricts a string column to T.Values() on scan, write and JSON decoding.
+type Enum[T EnumValues[T]] struct {
+	value T
+}
+
+func NewEnum[T EnumValues[T]](value T) *Enum[T] {
+	if err := validateEnum(value); err != nil {
+		panic(err)
+	}
+	return &Enum[T]{value}
+}
+
+func validateEnum[T EnumValues[T]](value T) error {
+	if !list.Contains(value, value.Values()) {
+		return fmt.Errorf("%w: '%s' is not one of %v", ErrInvalidEnum, string(value), value.Values())
+	}
+	return nil
+}
+
+func (e Enum[T]) Value() (driver.Value, error) {
+	if err := validateEnum(e.value); err != nil {
+		return nil, err
+	}
+	return string(e.value), nil
+}
+
+func (e *Enum[T]) Scan(value any) error {
+	var v string
+	if err := assign(&v, value); err != nil {
+		return err
+	}
+	if err := validateEnum(T(v)); err != nil {
+		return err
+	}
+	e.value = T(v)
+	return nil
+}
+
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(e.value))
+}
+
+func (e *Enum[T]) UnmarshalJSON(value []byte) error {
+	var v string
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	if err := validateEnum(T(v)); err != nil {
+		return err
+	}
+	e.value = T(v)
+	return nil
+}
+
+func (e Enum[T]) Unwrap() T {
+	return e.value
+}
@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatus string
+
+func (testStatus) Values() []testStatus {
+	return []testStatus{"new", "done"}
+}
+
+func TestEnum(t *testing.T) {
+	var status Enum[testStatus]
+	assert.Nil(t, status.Scan([]byte("done")))
+	assert.Equal(t, testStatus("done"), status.Unwrap())
+
+	err := status.Scan("lost")
+	assert.True(t, errors.Is(err, ErrInvalidEnum))
+	assert.EqualError(t, err, "invalid enum value: 'lost' is not one of [new done]")
+	assert.Equal(t, testStatus("done"), status.Unwrap())
+
+	value, err := NewEnum[testStatus]("new").Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "new", value)
+
+	_, err = (&Enum[testStatus]{}).Value()
+	assert.True(t, errors.Is(err, ErrInvalidEnum))
+	assert.Panics(t, func() { NewEnum[testStatus]("lost") })
+
+	assert.Nil(t, json.Unmarshal([]byte(`"new"`), &status))
+	assert.Equal(t, testStatus("new"), status.Unwrap())
+	assert.True(t, errors.Is(json.Unmarshal([]byte(`"lost"`), &status), ErrInvalidEnum))
+	data, err := json.Marshal(&status)
+	assert.Nil(t, err)
+	assert.Equal(t, `"new"`, string(data))
+	data, err = json.Marshal(struct {
+		Status Enum[testStatus] `json:"status"`
+	}{status})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"status":"new"}`, string(data))
+}
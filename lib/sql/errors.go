@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrNotFound        = fmt.Errorf("record not found")
+	ErrUniqueViolation = fmt.Errorf("unique violation")
+	ErrForeignKey      = fmt.Errorf("foreign key violation")
+	ErrSerialization   = fmt.Errorf("serialization failure")
+	ErrTimeout         = fmt.Errorf("query timeout")
+)
+
+var errorStates = map[string]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKey,
+	"40001": ErrSerialization, // serialization_failure
+	"40P01": ErrSerialization, // deadlock_detected
+	"57014": ErrTimeout,       // query_canceled, raised by statement_timeout
+}
+
+// Error is a classified database error. It matches both its sentinel Kind and the original driver error
+// with errors.Is and errors.As.
+type Error struct {
+	Kind       error
+	Code       string
+	Table      string
+	Column     string
+	Constraint string
+	Detail     string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("%v (%s): %v", e.Kind, e.Constraint, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Classify wraps known database errors into *Error; other errors, including nil, are returned as is.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		if kind, ok := errorStates[state.SQLState()]; ok {
+			result := &Error{Kind: kind, Code: state.SQLState(), Err: err}
+			result.details(state)
+			return result
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: ErrTimeout, Err: err}
+	}
+	return err
+}
+
+// details reads the table, column, constraint and detail fields of lib/pq (Get by field code) and pgx (struct fields)
+// errors without depending on either driver.
+func (e *Error) details(source any) {
+	if getter, ok := source.(interface{ Get(byte) string }); ok {
+		e.Table, e.Column, e.Constraint, e.Detail = getter.Get('t'), getter.Get('c'), getter.Get('n'), getter.Get('D')
+		return
+	}
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	field := func(names ...string) string {
+		for _, name := range names {
+			if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+				return f.String()
+			}
+		}
+		return ""
+	}
+	e.Table = field("TableName", "Table")
+	e.Column = field("ColumnName", "Column")
+	e.Constraint = field("ConstraintName", "Constraint")
+	e.Detail = field("Detail")
+}
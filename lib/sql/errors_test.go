@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fieldError struct {
+	stateError
+	fields map[byte]string
+}
+
+func (e fieldError) Get(field byte) string {
+	return e.fields[field]
+}
+
+type structError struct {
+	Code           string
+	ConstraintName string
+	ColumnName     string
+	TableName      string
+	Detail         string
+}
+
+func (e *structError) Error() string    { return "struct " + e.Code }
+func (e *structError) SQLState() string { return e.Code }
+
+func TestClassify(t *testing.T) {
+	t.Run(
+		"Sentinels",
+		func(t *testing.T) {
+			assert.Nil(t, Classify(nil))
+			assert.True(t, errors.Is(Classify(dbsql.ErrNoRows), ErrNotFound))
+			assert.True(t, errors.Is(Classify(dbsql.ErrNoRows), dbsql.ErrNoRows))
+			assert.True(t, errors.Is(Classify(stateError{"23503"}), ErrForeignKey))
+			assert.True(t, errors.Is(Classify(fmt.Errorf("wrapped: %w", stateError{"40P01"})), ErrSerialization))
+			assert.True(t, errors.Is(Classify(stateError{"57014"}), ErrTimeout))
+			assert.True(t, errors.Is(Classify(context.DeadlineExceeded), ErrTimeout))
+
+			other := fmt.Errorf("something went wrong")
+			assert.Same(t, other, Classify(other))
+			assert.Equal(t, stateError{"42P01"}, Classify(stateError{"42P01"}))
+		},
+	)
+
+	t.Run(
+		"Details",
+		func(t *testing.T) {
+			err := Classify(fieldError{stateError{"23505"}, map[byte]string{'t': "users", 'c': "email", 'n': "users_email_key", 'D': "Key (email)=(a) already exists."}})
+			var classified *Error
+			assert.True(t, errors.As(err, &classified))
+			assert.Equal(t, ErrUniqueViolation, classified.Kind)
+			assert.Equal(t, "23505", classified.Code)
+			assert.Equal(t, "users", classified.Table)
+			assert.Equal(t, "email", classified.Column)
+			assert.Equal(t, "users_email_key", classified.Constraint)
+			assert.EqualError(t, err, "unique violation (users_email_key): state 23505")
+			assert.Same(t, err, Classify(err))
+
+			err = Classify(&structError{Code: "23503", ConstraintName: "orders_user_fk", TableName: "orders"})
+			assert.True(t, errors.As(err, &classified))
+			assert.Equal(t, "orders_user_fk", classified.Constraint)
+			assert.Equal(t, "orders", classified.Table)
+			var original *structError
+			assert.True(t, errors.As(err, &original))
+		},
+	)
+}
@@ -0,0 +1,189 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Executor interface {
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	ExecContext(ctx context.Context, query string, args ...any) (dbsql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg any) (dbsql.Result, error)
+	QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+}
+
+type target interface {
+	sqlx.ExtContext
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	NamedExecContext(ctx context.Context, query string, arg any) (dbsql.Result, error)
+}
+
+type runner interface {
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	ExecContext(ctx context.Context, query string, args ...any) (dbsql.Result, error)
+	QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+}
+
+func NewExecutor(connection Db) *executor {
+	return &executor{connection: connection}
+}
+
+type executor struct {
+	connection Db
+	statements *statementCache
+}
+
+// Cache enables an LRU cache of up to size prepared statements per pool, keyed by the query text. Statements
+// are bound to the ambient transaction once per transaction, and the statements of a pool are dropped when its
+// connection reconnects.
+func (e *executor) Cache(size int) *executor {
+	if size <= 0 {
+		e.statements = nil
+		return e
+	}
+	e.statements = newStatementCache(size)
+	for _, notifier := range connectNotifiers(e.connection) {
+		notifier.OnConnect(e.statements.reconnected())
+	}
+	return e
+}
+
+func (e *executor) StatementStats() StatementStats {
+	if e.statements == nil {
+		return StatementStats{}
+	}
+	return e.statements.Stats()
+}
+
+func (e *executor) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, cancel := e.deadline(ctx)
+	defer cancel()
+	r, release, err := e.runner(ctx, query)
+	if err != nil {
+		return Classify(err)
+	}
+	defer release()
+	return Classify(r.SelectContext(ctx, dest, query, args...))
+}
+
+func (e *executor) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, cancel := e.deadline(ctx)
+	defer cancel()
+	r, release, err := e.runner(ctx, query)
+	if err != nil {
+		return Classify(err)
+	}
+	defer release()
+	return Classify(r.GetContext(ctx, dest, query, args...))
+}
+
+func (e *executor) ExecContext(ctx context.Context, query string, args ...any) (dbsql.Result, error) {
+	ctx, cancel := e.deadline(ctx)
+	defer cancel()
+	r, release, err := e.runner(ctx, query)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	defer release()
+	result, err := r.ExecContext(ctx, query, args...)
+	return result, Classify(err)
+}
+
+func (e *executor) NamedExecContext(ctx context.Context, query string, arg any) (dbsql.Result, error) {
+	ctx, cancel := e.deadline(ctx)
+	defer cancel()
+	result, err := e.target(ctx, query).NamedExecContext(ctx, query, arg)
+	return result, Classify(err)
+}
+
+// QueryxContext is not limited by the connection timeout, since the rows outlive the call; use a context
+// deadline to bound the iteration.
+func (e *executor) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	r, release, err := e.runner(ctx, query)
+	if err != nil {
+		return nil, Classify(err)
+	}
+	defer release()
+	rows, err := r.QueryxContext(ctx, query, args...)
+	return rows, Classify(err)
+}
+
+// runner returns the target for the query, or its cached prepared statement when the cache is enabled.
+func (e *executor) runner(ctx context.Context, query string) (runner, func(), error) {
+	t := e.target(ctx, query)
+	if e.statements == nil {
+		return t, func() {}, nil
+	}
+
+	pool, ok := t.(*sqlx.DB)
+	tx := ambientTransaction(ctx)
+	if tx != nil {
+		if tx.db != e.connection.Connect() {
+			return t, func() {}, nil
+		}
+		pool = tx.db
+	} else if !ok {
+		return t, func() {}, nil
+	}
+
+	if tx != nil {
+		stmt, err := tx.statement(ctx, query, func() (*sqlx.Stmt, func(), error) {
+			return e.statements.prepare(ctx, pool, query)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return preparedRunner{stmt}, func() {}, nil
+	}
+	stmt, release, err := e.statements.prepare(ctx, pool, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return preparedRunner{stmt}, release, nil
+}
+
+func (e *executor) target(ctx context.Context, query string) target {
+	read := isRead(query)
+	if !read {
+		markWritten(ctx)
+	}
+	if tx := TransactionFromContext(ctx); tx != nil {
+		return tx
+	}
+	if r, ok := e.connection.(reader); ok && read {
+		return r.Read(ctx)
+	}
+	return e.connection.Connect()
+}
+
+func (e *executor) deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := e.connection.Timeout(); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+type preparedRunner struct {
+	stmt *sqlx.Stmt
+}
+
+func (p preparedRunner) SelectContext(ctx context.Context, dest any, _ string, args ...any) error {
+	return p.stmt.SelectContext(ctx, dest, args...)
+}
+
+func (p preparedRunner) GetContext(ctx context.Context, dest any, _ string, args ...any) error {
+	return p.stmt.GetContext(ctx, dest, args...)
+}
+
+func (p preparedRunner) ExecContext(ctx context.Context, _ string, args ...any) (dbsql.Result, error) {
+	return p.stmt.ExecContext(ctx, args...)
+}
+
+func (p preparedRunner) QueryxContext(ctx context.Context, _ string, args ...any) (*sqlx.Rows, error) {
+	return p.stmt.QueryxContext(ctx, args...)
+}
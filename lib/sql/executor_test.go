@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+func TestExecutor(t *testing.T) {
+	db := sqltest.New()
+	db.On(`^select`).Rows([]string{"id"}, []any{1})
+	e := NewExecutor(db)
+	ctx := context.Background()
+
+	assert.Nil(t, TransactionFromContext(ctx))
+	query.Exec(ctx, e.ExecContext, query.NewBuilder("users").Delete())
+
+	txCtx, tx, commit, rollback := NewContextWithTransaction(ctx, db)
+	defer rollback()
+	assert.Same(t, tx, TransactionFromContext(txCtx))
+	assert.Equal(t, []int{1}, query.Query[[]int](txCtx, e.SelectContext, query.NewBuilder("users").Select("id").NotSort()))
+	assert.Equal(t, 1, query.Query[int](txCtx, e.GetContext, query.NewBuilder("users").Select("id").NotSort()))
+	_, err := e.NamedExecContext(txCtx, "update users set name=:name", map[string]any{"name": "a"})
+	assert.Nil(t, err)
+	commit()
+
+	assert.Nil(t, TransactionFromContext(txCtx))
+	query.Exec(txCtx, e.ExecContext, query.NewBuilder("orders").Delete())
+
+	var inTx []bool
+	for _, statement := range db.Statements() {
+		inTx = append(inTx, statement.Tx)
+	}
+	assert.Equal(t, []bool{false, true, true, true, false}, inTx)
+	assert.Equal(t, []string{"begin", "commit"}, db.Transactions())
+}
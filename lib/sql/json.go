@@ -26,11 +26,19 @@ func (f *Json[T]) Value() (driver.Value, error) {
 
 func (f *Json[T]) Scan(value any) error {
 	var v []byte
-	switch value.(type) {
+	switch value := value.(type) {
+	case nil:
+		var zero T
+		f.value = zero
+		return nil
+	case []byte:
+		v = value
+	case string:
+		v = []byte(value)
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
 		v = []byte(fmt.Sprintf("%v", value))
 	default:
-		v = value.([]byte)
+		return fmt.Errorf("cannot scan %T into %T", value, f)
 	}
 	return json.Unmarshal(v, &f.value)
 }
@@ -2,22 +2,19 @@ package sql
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"testing"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/betam/glb/lib/sql/sqltest"
 	"github.com/betam/glb/lib/try"
 )
 
 func TestJson(t *testing.T) {
-	mockDB, mock, err := sqlmock.New()
-	assert.Nil(t, err)
-	defer func(mockDB *sql.DB) { _ = mockDB.Close() }(mockDB)
-
-	dbMock := sqlx.NewDb(mockDB, "sqlmock")
+	db := sqltest.New()
+	dbMock := db.Connect()
+	defer func() { _ = dbMock.Close() }()
 
 	type J struct {
 		Name   string  `json:"name"`
@@ -41,7 +38,7 @@ func TestJson(t *testing.T) {
 			var document = []byte(`{"id":1,"value":{"name":"me","age":18,"weight":106},"int":150,"float":3.14,"bool":true}`)
 
 			var fromString Json[J]
-			err = json.Unmarshal(data, &fromString)
+			err := json.Unmarshal(data, &fromString)
 			assert.Nil(t, err)
 			structValue := J{
 				Name:   "me",
@@ -52,7 +49,7 @@ func TestJson(t *testing.T) {
 			assert.Equal(t, fromStruct, &fromString)
 			assert.Equal(t, structValue, fromString.Unwrap())
 
-			mock.ExpectQuery("select").WillReturnRows(sqlmock.NewRows([]string{"id", "value", "int", "float", "bool"}).AddRow(1, data, 150, 3.14, true))
+			db.On("^select").Rows([]string{"id", "value", "int", "float", "bool"}, []any{1, data, 150, 3.14, true})
 			assert.NotPanics(t, func() {
 				try.ThrowError(dbMock.GetContext(context.Background(), &result, "select * from somewhere where condition=?", 16))
 			})
@@ -83,12 +80,27 @@ func TestJson(t *testing.T) {
 				Weight: 106,
 			}
 
-			mock.ExpectExec("insert").WillReturnResult(sqlmock.NewResult(1, 1))
+			db.On("^insert").Affected(1)
 			assert.NotPanics(
 				t, func() {
 					try.Throw(dbMock.ExecContext(context.Background(), "insert into somewhere values (?, ?)", 1, NewJson(data), NewJson(150), NewJson(3.14), NewJson(true)))
 				},
 			)
+			assert.Equal(t, []any{int64(1), []byte(`{"name":"me","age":18,"weight":106}`), int64(150), 3.14, true}, db.Statements()[1].Args)
+		},
+	)
+
+	t.Run(
+		"ScanNullAndString",
+		func(t *testing.T) {
+			value := NewJson(J{Name: "me"})
+			assert.Nil(t, value.Scan(`{"name":"you","age":20}`))
+			assert.Equal(t, J{Name: "you", Age: 20}, value.Unwrap())
+
+			assert.Nil(t, value.Scan(nil))
+			assert.Equal(t, J{}, value.Unwrap())
+
+			assert.Error(t, value.Scan(struct{}{}))
 		},
 	)
 }
@@ -0,0 +1,120 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/pborman/getopt/v2"
+
+	"github.com/betam/glb/lib/app"
+	"github.com/betam/glb/lib/di"
+	"github.com/betam/glb/lib/sql"
+)
+
+// Register wires the migrate command under app.CommandTag. Migrations are read from source, or from dir when
+// source is nil; migrate create writes new files into dir.
+func Register(dir string, source fs.FS) {
+	if source == nil {
+		source = os.DirFS(dir)
+	}
+	di.Wire[app.Command](func(connection sql.Db) app.Command { return NewCommand(New(connection, source), dir) }, di.Tag(app.CommandTag))
+}
+
+func NewCommand(migrator *migrator, dir string) *command {
+	return &command{
+		BaseCommand: app.NewCommand("migrate", "Manage schema migrations: up [-n steps], down [-n steps], status, create <name>"),
+		migrator:    migrator,
+		dir:         dir,
+	}
+}
+
+type command struct {
+	*app.BaseCommand
+	migrator *migrator
+	dir      string
+}
+
+func (c *command) Run(ctx context.Context) {
+	if len(os.Args) < 2 {
+		panic(fmt.Errorf("migrate command is required: up, down, status or create"))
+	}
+	args := os.Args[1:]
+	switch args[0] {
+	case "up":
+		c.up(ctx, args)
+	case "down":
+		c.down(ctx, args)
+	case "status":
+		c.status(ctx)
+	case "create":
+		c.create(args)
+	default:
+		panic(fmt.Errorf("unknown migrate command '%s': expected up, down, status or create", args[0]))
+	}
+}
+
+func (c *command) up(ctx context.Context, args []string) {
+	steps := 0
+	opts := getopt.New()
+	opts.FlagLong(&steps, "steps", 'n', "Number of migrations to apply, all by default")
+	opts.Parse(args)
+
+	applied := c.migrator.Up(ctx, steps)
+	for _, migration := range applied {
+		fmt.Printf("%s %d_%s\n", color.GreenString("up"), migration.Version, migration.Name)
+	}
+	if len(applied) == 0 {
+		fmt.Println("Nothing to migrate")
+	}
+}
+
+func (c *command) down(ctx context.Context, args []string) {
+	steps := 1
+	opts := getopt.New()
+	opts.FlagLong(&steps, "steps", 'n', "Number of migrations to revert")
+	opts.Parse(args)
+
+	reverted := c.migrator.Down(ctx, steps)
+	for _, migration := range reverted {
+		fmt.Printf("%s %d_%s\n", color.YellowString("down"), migration.Version, migration.Name)
+	}
+	if len(reverted) == 0 {
+		fmt.Println("Nothing to revert")
+	}
+}
+
+func (c *command) status(ctx context.Context) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range c.migrator.Status(ctx) {
+		state, appliedAt := color.YellowString("pending"), ""
+		if status.AppliedAt != nil {
+			state, appliedAt = color.GreenString("applied"), status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state = color.RedString("modified")
+		}
+		if status.Missing {
+			state = color.RedString("missing")
+		}
+		_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	_ = writer.Flush()
+}
+
+func (c *command) create(args []string) {
+	opts := getopt.New()
+	opts.SetParameters("name")
+	opts.Parse(args)
+	if opts.NArgs() < 1 {
+		opts.PrintUsage(os.Stderr)
+		panic(fmt.Errorf("migration name is required"))
+	}
+
+	up, down := Create(c.dir, opts.Arg(0))
+	fmt.Printf("%s %s\n%s %s\n", color.GreenString("created"), up, color.GreenString("created"), down)
+}
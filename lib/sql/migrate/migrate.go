@@ -0,0 +1,286 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/betam/glb/lib/sql"
+	"github.com/betam/glb/lib/try"
+)
+
+const DefaultTable = "schema_migrations"
+
+var (
+	ErrChecksumMismatch = fmt.Errorf("applied migration has been modified")
+	ErrMissingMigration = fmt.Errorf("applied migration is missing")
+	ErrNoDownMigration  = fmt.Errorf("migration has no down script")
+)
+
+var filePattern = regexp.MustCompile(`^(\d+)_([^.]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool
+	Missing   bool
+}
+
+type applied struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Load reads migrations named <version>_<name>.up.sql and <version>_<name>.down.sql from the root of the source,
+// ordered by version. Other files are ignored.
+func Load(source fs.FS) []Migration {
+	entries := try.Throw(fs.ReadDir(source, "."))
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version := try.Throw(strconv.ParseInt(match[1], 10, 64))
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			migrations[version] = migration
+		} else if migration.Name != match[2] {
+			panic(fmt.Errorf("migration %d has different names '%s' and '%s'", version, migration.Name, match[2]))
+		}
+		content := string(try.Throw(fs.ReadFile(source, entry.Name())))
+		if match[3] == "up" {
+			migration.Up = content
+		} else {
+			migration.Down = content
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Up == "" {
+			panic(fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name))
+		}
+		migration.Checksum = checksum(migration.Up)
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result
+}
+
+// Create writes empty up and down scripts for a new migration into the directory and returns their paths.
+func Create(dir string, name string) (string, string) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		panic(fmt.Errorf("migration name is required"))
+	}
+	try.ThrowError(os.MkdirAll(dir, 0o755))
+	prefix := filepath.Join(dir, fmt.Sprintf("%s_%s", time.Now().UTC().Format("20060102150405"), name))
+	up, down := prefix+".up.sql", prefix+".down.sql"
+	for _, path := range []string{up, down} {
+		file := try.Throw(os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644))
+		try.ThrowError(file.Close())
+	}
+	return up, down
+}
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func New(connection sql.Db, source fs.FS) *migrator {
+	return &migrator{
+		connection: connection,
+		source:     source,
+		table:      DefaultTable,
+	}
+}
+
+type migrator struct {
+	connection sql.Db
+	source     fs.FS
+	table      string
+}
+
+func (m *migrator) Table(table string) *migrator {
+	m.table = table
+	return m
+}
+
+// Up applies up to steps pending migrations (all of them when steps <= 0), each in its own transaction.
+func (m *migrator) Up(ctx context.Context, steps int) []Migration {
+	var done []Migration
+	m.locked(ctx, func(conn *sqlx.Conn) {
+		migrations := Load(m.source)
+		history := m.history(ctx, conn)
+		m.verify(migrations, history)
+		for _, migration := range migrations {
+			if steps > 0 && len(done) >= steps {
+				break
+			}
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			m.apply(ctx, conn, migration.Up, func(tx *sqlx.Tx) {
+				_ = try.Throw(tx.ExecContext(
+					ctx,
+					fmt.Sprintf("insert into %s (version, name, checksum) values ($1, $2, $3)", m.table),
+					migration.Version, migration.Name, migration.Checksum,
+				))
+			})
+			done = append(done, migration)
+		}
+	})
+	return done
+}
+
+// Down reverts up to steps applied migrations (one when steps <= 0), latest first.
+func (m *migrator) Down(ctx context.Context, steps int) []Migration {
+	if steps <= 0 {
+		steps = 1
+	}
+	var done []Migration
+	m.locked(ctx, func(conn *sqlx.Conn) {
+		migrations := Load(m.source)
+		history := m.history(ctx, conn)
+		m.verify(migrations, history)
+		for idx := len(migrations) - 1; idx >= 0 && len(done) < steps; idx-- {
+			migration := migrations[idx]
+			if _, ok := history[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				panic(fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name))
+			}
+			m.apply(ctx, conn, migration.Down, func(tx *sqlx.Tx) {
+				_ = try.Throw(tx.ExecContext(ctx, fmt.Sprintf("delete from %s where version = $1", m.table), migration.Version))
+			})
+			done = append(done, migration)
+		}
+	})
+	return done
+}
+
+// Status lists known migrations with their applied time, followed by applied versions missing in the source.
+// It only reads, so it neither takes the advisory lock nor creates the migrations table.
+func (m *migrator) Status(ctx context.Context) []Status {
+	var result []Status
+	m.connected(ctx, func(conn *sqlx.Conn) {
+		history := map[int64]applied{}
+		var exists bool
+		try.ThrowError(conn.GetContext(ctx, &exists, "select to_regclass($1) is not null", m.table))
+		if exists {
+			history = m.history(ctx, conn)
+		}
+		for _, migration := range Load(m.source) {
+			status := Status{Migration: migration}
+			if record, ok := history[migration.Version]; ok {
+				appliedAt := record.AppliedAt
+				status.AppliedAt = &appliedAt
+				status.Modified = record.Checksum != migration.Checksum
+				delete(history, migration.Version)
+			}
+			result = append(result, status)
+		}
+		for _, record := range history {
+			appliedAt := record.AppliedAt
+			result = append(result, Status{
+				Migration: Migration{Version: record.Version, Name: record.Name, Checksum: record.Checksum},
+				AppliedAt: &appliedAt,
+				Missing:   true,
+			})
+		}
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result
+}
+
+// locked runs the callback on a dedicated connection holding a session advisory lock derived from the table name,
+// so concurrent deployments apply migrations one at a time.
+func (m *migrator) locked(ctx context.Context, callback func(conn *sqlx.Conn)) {
+	m.connected(ctx, func(conn *sqlx.Conn) {
+		key := m.lockKey()
+		_ = try.Throw(conn.ExecContext(ctx, "select pg_advisory_lock($1)", key))
+		defer func() { _, _ = conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", key) }()
+
+		_ = try.Throw(conn.ExecContext(ctx, fmt.Sprintf(`create table if not exists %s (
+	version bigint primary key,
+	name text not null,
+	checksum text not null,
+	applied_at timestamptz not null default now()
+)`, m.table)))
+		callback(conn)
+	})
+}
+
+func (m *migrator) connected(ctx context.Context, callback func(conn *sqlx.Conn)) {
+	conn := try.Throw(m.connection.Connect().Connx(ctx))
+	defer func() { _ = conn.Close() }()
+	callback(conn)
+}
+
+func (m *migrator) lockKey() int64 {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(m.table))
+	return int64(hasher.Sum64())
+}
+
+func (m *migrator) history(ctx context.Context, conn *sqlx.Conn) map[int64]applied {
+	var records []applied
+	try.ThrowError(conn.SelectContext(ctx, &records, fmt.Sprintf("select version, name, checksum, applied_at from %s order by version", m.table)))
+	history := make(map[int64]applied, len(records))
+	for _, record := range records {
+		history[record.Version] = record
+	}
+	return history
+}
+
+func (m *migrator) verify(migrations []Migration, history map[int64]applied) {
+	known := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+	for version, record := range history {
+		migration, ok := known[version]
+		if !ok {
+			panic(fmt.Errorf("%w: %d_%s", ErrMissingMigration, record.Version, record.Name))
+		}
+		if migration.Checksum != record.Checksum {
+			panic(fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name))
+		}
+	}
+}
+
+func (m *migrator) apply(ctx context.Context, conn *sqlx.Conn, script string, record func(tx *sqlx.Tx)) {
+	tx := try.Throw(conn.BeginTxx(ctx, nil))
+	defer func() { _ = tx.Rollback() }()
+	if strings.TrimSpace(script) != "" {
+		_ = try.Throw(tx.ExecContext(ctx, script))
+	}
+	record(tx)
+	try.ThrowError(tx.Commit())
+}
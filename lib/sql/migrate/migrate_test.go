@@ -0,0 +1,276 @@
+package migrate
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// historyDb is an in-memory sql.Db keeping the migrations table and recording the other statements.
+type historyDb struct {
+	db         *sqlx.DB
+	applied    map[int64][]driver.Value
+	statements []string
+}
+
+func newHistoryDb() *historyDb {
+	d := &historyDb{applied: map[int64][]driver.Value{}}
+	d.db = sqlx.NewDb(dbsql.OpenDB(historyConnector{d}), "fake")
+	return d
+}
+
+func (d *historyDb) Connect() *sqlx.DB      { return d.db }
+func (d *historyDb) Timeout() time.Duration { return 0 }
+
+type historyConnector struct {
+	db *historyDb
+}
+
+func (c historyConnector) Connect(context.Context) (driver.Conn, error) {
+	return historyConn{c.db}, nil
+}
+func (c historyConnector) Driver() driver.Driver            { return c }
+func (c historyConnector) Open(string) (driver.Conn, error) { return historyConn{c.db}, nil }
+
+type historyConn struct {
+	db *historyDb
+}
+
+func (c historyConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (c historyConn) Close() error                        { return nil }
+
+func (c historyConn) Begin() (driver.Tx, error) {
+	c.db.statements = append(c.db.statements, "begin")
+	return c, nil
+}
+
+func (c historyConn) Commit() error {
+	c.db.statements = append(c.db.statements, "commit")
+	return nil
+}
+
+func (c historyConn) Rollback() error {
+	return nil
+}
+
+func (c historyConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "insert into "+DefaultTable):
+		c.db.applied[args[0].Value.(int64)] = []driver.Value{args[0].Value, args[1].Value, args[2].Value, time.Now()}
+	case strings.HasPrefix(query, "delete from "+DefaultTable):
+		delete(c.db.applied, args[0].Value.(int64))
+	case strings.HasPrefix(query, "create table"):
+		c.db.statements = append(c.db.statements, "create table")
+	default:
+		c.db.statements = append(c.db.statements, query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c historyConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.HasPrefix(query, "select to_regclass") {
+		return &historyRows{columns: []string{"exists"}, rows: [][]driver.Value{{len(c.db.applied) > 0}}}, nil
+	}
+	rows := &historyRows{columns: []string{"version", "name", "checksum", "applied_at"}}
+	for _, row := range c.db.applied {
+		rows.rows = append(rows.rows, row)
+	}
+	sort.Slice(rows.rows, func(i, j int) bool { return rows.rows[i][0].(int64) < rows.rows[j][0].(int64) })
+	return rows, nil
+}
+
+type historyRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *historyRows) Columns() []string { return r.columns }
+func (r *historyRows) Close() error      { return nil }
+
+func (r *historyRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var fixtures = fstest.MapFS{
+	"1_users.up.sql":       {Data: []byte("create users")},
+	"1_users.down.sql":     {Data: []byte("drop users")},
+	"2_add_email.up.sql":   {Data: []byte("add email")},
+	"2_add_email.down.sql": {Data: []byte("drop email")},
+	"3_seed.up.sql":        {Data: []byte("seed")},
+}
+
+func TestLoad(t *testing.T) {
+	t.Run(
+		"Ordered",
+		func(t *testing.T) {
+			migrations := Load(fstest.MapFS{
+				"2_add_email.up.sql":   {Data: []byte("alter table users add email text")},
+				"2_add_email.down.sql": {Data: []byte("alter table users drop email")},
+				"1_users.up.sql":       {Data: []byte("create table users (id bigserial primary key)")},
+				"README.md":            {Data: []byte("ignored")},
+			})
+			assert.Len(t, migrations, 2)
+			assert.Equal(t, int64(1), migrations[0].Version)
+			assert.Equal(t, "users", migrations[0].Name)
+			assert.Equal(t, "", migrations[0].Down)
+			assert.Equal(t, "add_email", migrations[1].Name)
+			assert.Equal(t, "alter table users drop email", migrations[1].Down)
+			assert.Equal(t, checksum("alter table users add email text"), migrations[1].Checksum)
+		},
+	)
+
+	t.Run(
+		"Malformed",
+		func(t *testing.T) {
+			assert.PanicsWithError(t, "migration 1_users has no up script", func() {
+				Load(fstest.MapFS{"1_users.down.sql": {Data: []byte("drop table users")}})
+			})
+			assert.PanicsWithError(t, "migration 1 has different names 'accounts' and 'users'", func() {
+				Load(fstest.MapFS{"1_users.up.sql": {Data: []byte("-")}, "1_accounts.up.sql": {Data: []byte("-")}})
+			})
+		},
+	)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	up, down := Create(dir, "Add Users!")
+	assert.Regexp(t, `^\d{14}_add_users\.up\.sql$`, filepath.Base(up))
+	assert.Regexp(t, `^\d{14}_add_users\.down\.sql$`, filepath.Base(down))
+
+	assert.NoError(t, os.WriteFile(up, []byte("create table users (id bigserial primary key)"), 0o644))
+	assert.Len(t, Load(os.DirFS(dir)), 1)
+	assert.PanicsWithError(t, "migration name is required", func() { Create(dir, "!!") })
+}
+
+func TestVerify(t *testing.T) {
+	m := New(nil, nil)
+	migrations := []Migration{{Version: 1, Name: "users", Checksum: "a"}}
+
+	assert.NotPanics(t, func() { m.verify(migrations, map[int64]applied{1: {Version: 1, Name: "users", Checksum: "a"}}) })
+	assertPanicsIs(t, ErrChecksumMismatch, func() { m.verify(migrations, map[int64]applied{1: {Version: 1, Name: "users", Checksum: "b"}}) })
+	assertPanicsIs(t, ErrMissingMigration, func() { m.verify(migrations, map[int64]applied{2: {Version: 2, Name: "gone"}}) })
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run(
+		"Up",
+		func(t *testing.T) {
+			db := newHistoryDb()
+			m := New(db, fixtures)
+			ctx := context.Background()
+
+			assert.Equal(t, []int64{1}, versions(m.Up(ctx, 1)))
+			assert.Equal(t, []int64{2, 3}, versions(m.Up(ctx, 0)))
+			assert.Empty(t, m.Up(ctx, 0))
+			assert.Len(t, db.applied, 3)
+			assert.Equal(
+				t,
+				[]string{
+					"select pg_advisory_lock($1)", "create table", "begin", "create users", "commit", "select pg_advisory_unlock($1)",
+					"select pg_advisory_lock($1)", "create table", "begin", "add email", "commit", "begin", "seed", "commit", "select pg_advisory_unlock($1)",
+					"select pg_advisory_lock($1)", "create table", "select pg_advisory_unlock($1)",
+				},
+				db.statements,
+			)
+		},
+	)
+
+	t.Run(
+		"Down",
+		func(t *testing.T) {
+			db := newHistoryDb()
+			m := New(db, fixtures)
+			ctx := context.Background()
+			m.Up(ctx, 2)
+			db.statements = nil
+
+			assert.Equal(t, []int64{2, 1}, versions(m.Down(ctx, 5)))
+			assert.Empty(t, db.applied)
+			assert.Empty(t, m.Down(ctx, 1))
+			assert.Equal(
+				t,
+				[]string{
+					"select pg_advisory_lock($1)", "create table", "begin", "drop email", "commit", "begin", "drop users", "commit", "select pg_advisory_unlock($1)",
+					"select pg_advisory_lock($1)", "create table", "select pg_advisory_unlock($1)",
+				},
+				db.statements,
+			)
+
+			m.Up(ctx, 0)
+			assertPanicsIs(t, ErrNoDownMigration, func() { m.Down(ctx, 1) })
+			assert.Len(t, db.applied, 3)
+		},
+	)
+
+	t.Run(
+		"Status",
+		func(t *testing.T) {
+			db := newHistoryDb()
+			m := New(db, fixtures)
+			ctx := context.Background()
+
+			assert.Len(t, m.Status(ctx), 3)
+			assert.Empty(t, db.statements)
+
+			m.Up(ctx, 1)
+			db.statements = nil
+			statuses := m.Status(ctx)
+			assert.NotNil(t, statuses[0].AppliedAt)
+			assert.Nil(t, statuses[1].AppliedAt)
+			assert.Empty(t, db.statements)
+		},
+	)
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	args := os.Args
+	defer func() { os.Args = args }()
+	c := NewCommand(New(nil, nil), dir)
+	assert.Equal(t, "migrate", c.Name())
+
+	os.Args = []string{"migrate", "create", "add users"}
+	c.Run(context.Background())
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 2)
+
+	os.Args = []string{"migrate", "redo"}
+	assert.PanicsWithError(t, "unknown migrate command 'redo': expected up, down, status or create", func() { c.Run(context.Background()) })
+	os.Args = []string{"migrate"}
+	assert.PanicsWithError(t, "migrate command is required: up, down, status or create", func() { c.Run(context.Background()) })
+}
+
+func versions(migrations []Migration) []int64 {
+	var result []int64
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func assertPanicsIs(t *testing.T, target error, f func()) {
+	defer func() {
+		err, _ := recover().(error)
+		assert.True(t, errors.Is(err, target), "expected %v, got %v", target, err)
+	}()
+	f()
+}
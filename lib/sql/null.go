@@ -0,0 +1,67 @@
+package sql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Null is a nullable column value, marshalled to JSON null when not valid. Like sql.Null, it writes and marshals
+// by value and scans and unmarshals through a pointer.
+type Null[T any] struct {
+	value T
+	valid bool
+}
+
+func NewNull[T any](value T) *Null[T] {
+	return &Null[T]{value: value, valid: true}
+}
+
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.valid {
+		return nil, nil
+	}
+	return convert(n.value)
+}
+
+func (n *Null[T]) Scan(value any) error {
+	var v T
+	if value == nil {
+		n.value, n.valid = v, false
+		return nil
+	}
+	if err := assign(&v, value); err != nil {
+		return err
+	}
+	n.value, n.valid = v, true
+	return nil
+}
+
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.value)
+}
+
+func (n *Null[T]) UnmarshalJSON(value []byte) error {
+	var v T
+	if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+		n.value, n.valid = v, false
+		return nil
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	n.value, n.valid = v, true
+	return nil
+}
+
+func (n Null[T]) Valid() bool {
+	return n.valid
+}
+
+// Unwrap returns the value, or the zero value of T when it is null.
+func (n Null[T]) Unwrap() T {
+	return n.value
+}
@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+func TestNull(t *testing.T) {
+	t.Run(
+		"Scan",
+		func(t *testing.T) {
+			var number Null[int]
+			assert.Nil(t, number.Scan(int64(7)))
+			assert.True(t, number.Valid())
+			assert.Equal(t, 7, number.Unwrap())
+
+			assert.Nil(t, number.Scan([]byte("12")))
+			assert.Equal(t, 12, number.Unwrap())
+
+			assert.Nil(t, number.Scan(nil))
+			assert.False(t, number.Valid())
+			assert.Equal(t, 0, number.Unwrap())
+
+			var text Null[string]
+			assert.Nil(t, text.Scan([]byte("value")))
+			assert.Equal(t, "value", text.Unwrap())
+			assert.Error(t, text.Scan(int64(1)))
+
+			var moment Null[time.Time]
+			now := time.Now()
+			assert.Nil(t, moment.Scan(now))
+			assert.Equal(t, now, moment.Unwrap())
+		},
+	)
+
+	t.Run(
+		"Value",
+		func(t *testing.T) {
+			value, err := NewNull(int32(5)).Value()
+			assert.Nil(t, err)
+			assert.Equal(t, int64(5), value)
+
+			value, err = (&Null[int]{}).Value()
+			assert.Nil(t, err)
+			assert.Nil(t, value)
+		},
+	)
+
+	t.Run(
+		"Json",
+		func(t *testing.T) {
+			type R struct {
+				A *Null[string] `json:"a"`
+				B *Null[string] `json:"b"`
+			}
+			data, err := json.Marshal(R{A: NewNull("x"), B: &Null[string]{}})
+			assert.Nil(t, err)
+			assert.Equal(t, `{"a":"x","b":null}`, string(data))
+
+			type V struct {
+				A Null[string] `json:"a"`
+				B Null[int]    `json:"b"`
+			}
+			data, err = json.Marshal(V{A: *NewNull("x")})
+			assert.Nil(t, err)
+			assert.Equal(t, `{"a":"x","b":null}`, string(data))
+
+			var result struct {
+				A Null[string] `json:"a"`
+				B Null[string] `json:"b"`
+			}
+			assert.Nil(t, json.Unmarshal([]byte(`{"a":"x","b":null}`), &result))
+			assert.True(t, result.A.Valid())
+			assert.Equal(t, "x", result.A.Unwrap())
+			assert.False(t, result.B.Valid())
+		},
+	)
+}
+
+func TestNullInsert(t *testing.T) {
+	type row struct {
+		Name   Null[string]     `db:"name"`
+		Age    Null[int]        `db:"age"`
+		Status Enum[testStatus] `db:"status"`
+	}
+	db := sqltest.New()
+	query.Exec(context.Background(), db.Connect().ExecContext, query.NewBuilder("users").Insert().Values(row{Name: *NewNull("a"), Status: *NewEnum[testStatus]("new")}))
+
+	assert.Equal(t, []string{"insert into users (name, age, status) values ($1, $2, $3)"}, db.Queries())
+	assert.Equal(t, []any{"a", nil, "new"}, db.Statements()[0].Args)
+}
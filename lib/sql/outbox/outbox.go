@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/betam/glb/lib/sql"
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/try"
+	"github.com/betam/glb/lib2/amqpman"
+)
+
+const DefaultTable = "outbox"
+
+type Publisher func(ctx context.Context, exchange string, routingKey string, payload []byte) error
+
+// AmqpPublisher puts the channel in confirm mode, so a message counts as published only once the broker has
+// acknowledged it.
+func AmqpPublisher(channel *amqp.Channel) Publisher {
+	try.ThrowError(channel.Confirm(false))
+	return func(ctx context.Context, exchange string, routingKey string, payload []byte) error {
+		return amqpman.PublishConfirmed(channel, ctx, exchange, routingKey, payload)
+	}
+}
+
+type Message struct {
+	Id          int64      `db:"id,readonly"`
+	Exchange    string     `db:"exchange"`
+	RoutingKey  string     `db:"routing_key"`
+	Payload     []byte     `db:"payload"`
+	Attempts    int        `db:"attempts"`
+	CreatedAt   time.Time  `db:"created_at,omitempty"`
+	PublishedAt *time.Time `db:"published_at,omitempty"`
+}
+
+func Schema(table string) string {
+	return fmt.Sprintf(`create table if not exists %[1]s (
+	id bigserial primary key,
+	exchange text not null,
+	routing_key text not null,
+	payload bytea not null,
+	attempts integer not null default 0,
+	created_at timestamptz not null default now(),
+	published_at timestamptz
+);
+create index if not exists %[1]s_unpublished on %[1]s (id) where published_at is null;`, table)
+}
+
+func New(connection sql.Db, table string) *outbox {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &outbox{
+		connection: connection,
+		executor:   sql.NewExecutor(connection),
+		table:      table,
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+type outbox struct {
+	connection sql.Db
+	executor   sql.Executor
+	table      string
+	notify     chan struct{}
+}
+
+// Write stores the message in the ambient transaction; the relay is woken up once the transaction commits.
+func (o *outbox) Write(ctx context.Context, exchange string, routingKey string, payload []byte) {
+	query.Exec(ctx, o.executor.ExecContext, query.NewBuilder(o.table).Insert().Values(&Message{
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+		Payload:    payload,
+	}))
+	sql.AfterCommit(ctx, o.wake)
+}
+
+// Relay publishes up to batch pending messages in order and marks them as published. Messages are locked
+// for the duration of the pass, so several relays may run concurrently; delivery is at least once.
+func (o *outbox) Relay(ctx context.Context, publish Publisher, batch int) int {
+	published := 0
+	try.ThrowError(sql.InTransaction(ctx, o.connection, &sql.TransactionOptions{}, func(ctx context.Context) error {
+		messages := query.Query[[]Message](
+			ctx,
+			o.executor.SelectContext,
+			query.NewBuilder(o.table).Select().Lock("update skip locked").Where(query.And("published_at", "eq", nil)).Sort("id asc").Page(0, batch),
+		)
+		var done []int64
+		for _, message := range messages {
+			if err := publish(ctx, message.Exchange, message.RoutingKey, message.Payload); err != nil {
+				logrus.WithContext(ctx).Warnf("outbox message %d is not published: %v", message.Id, err)
+				query.Exec(ctx, o.executor.ExecContext, query.NewBuilder(o.table).Update(map[string]any{"attempts": query.Raw("attempts + 1")}).Where(query.And("id", "eq", message.Id)))
+				break
+			}
+			done = append(done, message.Id)
+		}
+		if len(done) > 0 {
+			query.Exec(ctx, o.executor.ExecContext, query.NewBuilder(o.table).Update(map[string]any{"published_at": query.Raw("now()")}).Where(query.And("id", "in", done)))
+		}
+		published = len(done)
+		return nil
+	}))
+	return published
+}
+
+// Run relays messages until the context is cancelled, polling every interval and right after Write commits.
+func (o *outbox) Run(ctx context.Context, publish Publisher, batch int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		try.Catch(
+			func() {
+				for {
+					if published := o.Relay(ctx, publish, batch); batch <= 0 || published < batch || ctx.Err() != nil {
+						return
+					}
+				}
+			},
+			func(throwable error) {
+				logrus.WithContext(ctx).Error(throwable)
+			},
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.notify:
+		}
+	}
+}
+
+func (o *outbox) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (o *outbox) Pending(ctx context.Context) int {
+	return query.Query[int](ctx, o.executor.GetContext, query.NewBuilder(o.table).Select("count(*)").Where(query.And("published_at", "eq", nil)).NotSort())
+}
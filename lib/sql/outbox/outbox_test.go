@@ -0,0 +1,179 @@
+package outbox
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql"
+)
+
+// outboxDb is an in-memory sql.Db recording statements; queries matching a pattern return its rows.
+type outboxDb struct {
+	mutex      sync.Mutex
+	db         *sqlx.DB
+	rows       map[string]*outboxRows
+	statements []string
+}
+
+func newOutboxDb(rows map[string]*outboxRows) *outboxDb {
+	d := &outboxDb{rows: rows}
+	d.db = sqlx.NewDb(dbsql.OpenDB(outboxConnector{d}), "fake")
+	return d
+}
+
+func (d *outboxDb) Connect() *sqlx.DB      { return d.db }
+func (d *outboxDb) Timeout() time.Duration { return 0 }
+
+func (d *outboxDb) record(query string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.statements = append(d.statements, query)
+}
+
+func (d *outboxDb) executed() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string{}, d.statements...)
+}
+
+type outboxConnector struct {
+	db *outboxDb
+}
+
+func (c outboxConnector) Connect(context.Context) (driver.Conn, error) { return outboxConn{c.db}, nil }
+func (c outboxConnector) Driver() driver.Driver                        { return c }
+func (c outboxConnector) Open(string) (driver.Conn, error)             { return outboxConn{c.db}, nil }
+
+type outboxConn struct {
+	db *outboxDb
+}
+
+func (c outboxConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (c outboxConn) Close() error                        { return nil }
+
+func (c outboxConn) Begin() (driver.Tx, error) {
+	c.db.record("begin")
+	return c, nil
+}
+
+func (c outboxConn) Commit() error {
+	c.db.record("commit")
+	return nil
+}
+
+func (c outboxConn) Rollback() error {
+	c.db.record("rollback")
+	return nil
+}
+
+func (c outboxConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c outboxConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.record(query)
+	for pattern, rows := range c.db.rows {
+		if regexp.MustCompile(pattern).MatchString(query) {
+			return &outboxRows{columns: rows.columns, rows: rows.rows}, nil
+		}
+	}
+	return &outboxRows{}, nil
+}
+
+type outboxRows struct {
+	columns []string
+	rows    [][]any
+}
+
+func (r *outboxRows) Columns() []string { return r.columns }
+
+func (r *outboxRows) Close() error { return nil }
+
+func (r *outboxRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for idx, value := range r.rows[0] {
+		dest[idx] = value
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run(
+		"Write",
+		func(t *testing.T) {
+			db := newOutboxDb(nil)
+			o := New(db, "")
+
+			ctx, _, commit, rollback := sql.NewContextWithTransaction(context.Background(), db)
+			defer rollback()
+			o.Write(ctx, "events", "user.created", []byte("{}"))
+			assert.Len(t, o.notify, 0)
+			commit()
+			assert.Len(t, o.notify, 1)
+
+			assert.Equal(t, []string{"begin", "insert into outbox (exchange, routing_key, payload, attempts) values ($1, $2, $3, $4)", "commit"}, db.executed())
+		},
+	)
+
+	t.Run(
+		"Relay",
+		func(t *testing.T) {
+			created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			db := newOutboxDb(map[string]*outboxRows{
+				"^select": {
+					columns: []string{"id", "exchange", "routing_key", "payload", "attempts", "created_at", "published_at"},
+					rows: [][]any{
+						{int64(1), "events", "a", []byte("1"), int64(0), created, nil},
+						{int64(2), "events", "b", []byte("2"), int64(0), created, nil},
+						{int64(3), "events", "c", []byte("3"), int64(0), created, nil},
+					},
+				},
+			})
+			var published []string
+			publish := func(ctx context.Context, exchange string, routingKey string, payload []byte) error {
+				if routingKey == "b" {
+					return fmt.Errorf("nack")
+				}
+				published = append(published, routingKey)
+				return nil
+			}
+
+			assert.Equal(t, 1, New(db, "messages").Relay(context.Background(), publish, 10))
+			assert.Equal(t, []string{"a"}, published)
+			assert.Equal(
+				t,
+				[]string{
+					"begin",
+					"select * from messages where (published_at is null) order by id asc limit 10 for update skip locked",
+					"update messages set attempts=attempts + 1 where (id = $1)",
+					"update messages set published_at=now() where (id in ($1))",
+					"commit",
+				},
+				db.executed(),
+			)
+		},
+	)
+
+	t.Run(
+		"Pending",
+		func(t *testing.T) {
+			db := newOutboxDb(map[string]*outboxRows{"^select count": {columns: []string{"count"}, rows: [][]any{{int64(4)}}}})
+			assert.Equal(t, 4, New(db, "").Pending(context.Background()))
+			assert.Equal(t, []string{"select count(*) from outbox where (published_at is null)"}, db.executed())
+		},
+	)
+}
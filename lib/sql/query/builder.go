@@ -1,8 +1,10 @@
 package query
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/betam/glb/lib/list"
@@ -16,11 +18,20 @@ const (
 	modeUpdate
 
 	doNotSort = "doNotSort"
+
+	operationUnion     = "union"
+	operationUnionAll  = "union all"
+	operationIntersect = "intersect"
+	operationExcept    = "except"
 )
 
 const mainTblAlias = "maintbl"
 
+var ErrStaleVersion = fmt.Errorf("stale version")
+
 type Builder interface {
+	With(name string, builder Builder) Builder
+	WithRecursive(name string, builder Builder) Builder
 	SubTable(Builder) Builder
 	Where(Expression) Builder
 	Page(int, int) Builder
@@ -30,7 +41,13 @@ type Builder interface {
 	Build() (string, *[]any)
 	Returning(fields ...string) Builder
 	Group(fields ...string) Builder
+	After(cursor *Cursor) Builder
+	Before(cursor *Cursor) Builder
+	Cursor(row any) *Cursor
+	Unscoped(scopes ...string) Builder
 	named(value bool) Builder
+	scoped(ctx context.Context) Builder
+	stale() error
 	parameters(*[]any) Builder
 }
 
@@ -42,7 +59,16 @@ type InsertBuilder interface {
 
 type SelectBuilder interface {
 	Builder
+	As(alias string) SelectBuilder
 	Join(mode string, table string, alias string, on string) SelectBuilder
+	JoinOn(mode string, table string, alias string, on Expression) SelectBuilder
+	JoinSub(mode string, subquery Builder, alias string, on Expression) SelectBuilder
+	Having(Expression) SelectBuilder
+	Lock(clause string) SelectBuilder
+	Union(other Builder) SelectBuilder
+	UnionAll(other Builder) SelectBuilder
+	Intersect(other Builder) SelectBuilder
+	Except(other Builder) SelectBuilder
 }
 
 type TableBuilder interface {
@@ -50,7 +76,13 @@ type TableBuilder interface {
 	Select(fields ...string) SelectBuilder
 	Delete() Builder
 	Insert(fields ...string) InsertBuilder
-	Update(values map[string]any) Builder
+	Update(values map[string]any) UpdateBuilder
+	UpdateStruct(value any) UpdateBuilder
+}
+
+type UpdateBuilder interface {
+	Builder
+	Versioned(column string, expected any) UpdateBuilder
 }
 
 func NewBuilder(table ...string) TableBuilder {
@@ -80,28 +112,73 @@ func (b *builder) Insert(fields ...string) InsertBuilder {
 	return b
 }
 
-func (b *builder) Update(values map[string]any) Builder {
+func (b *builder) Update(values map[string]any) UpdateBuilder {
 	b.queryMode = modeUpdate
-	b.updates = values
+	fields := list.Keys(values)
+	sort.Strings(fields)
+	b.updates = list.Map(fields, func(field string) *assignment { return &assignment{field: field, value: values[field]} })
 	return b
 }
 
+func (b *builder) UpdateStruct(value any) UpdateBuilder {
+	b.queryMode = modeUpdate
+	b.updates = structAssignments(value)
+	return b
+}
+
+// Versioned makes the update optimistic: it applies only while the column still holds the expected value and
+// increments the column. Query and Exec throw ErrStaleVersion when no row matches.
+func (b *builder) Versioned(column string, expected any) UpdateBuilder {
+	b.version = &assignment{field: column, value: expected}
+	return b
+}
+
+func (b *builder) stale() error {
+	if b.version == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s.%s is not %v", ErrStaleVersion, b.table, b.version.field, b.version.value)
+}
+
+type assignment struct {
+	field string
+	value any
+}
+
+type cte struct {
+	name    string
+	builder Builder
+}
+
+type compound struct {
+	operation string
+	builder   Builder
+}
+
 type join struct {
-	mode  string
-	table string
-	alias string
-	on    string
+	mode      string
+	table     string
+	subquery  Builder
+	alias     string
+	on        string
+	condition Expression
+	scopes    []Expression
 }
 
 type builder struct {
 	queryMode      uint8
+	with           []*cte
+	recursive      bool
 	fields         []string
 	table          string
+	alias          string
 	subSelect      Builder
 	inserts        [][]any
-	updates        map[string]any
+	structs        []any
+	updates        []*assignment
 	where          Expression
 	join           []*join
+	compound       []*compound
 	window         string
 	page           int
 	count          int
@@ -112,6 +189,14 @@ type builder struct {
 	namedMode      bool
 	params         *[]any
 	group          []string
+	having         Expression
+	cursor         *Cursor
+	backward       bool
+	lock           string
+	version        *assignment
+	unscoped       []string
+	scopes         []Expression
+	scopeValues    map[string]any
 }
 
 func (b *builder) Group(fields ...string) Builder {
@@ -134,11 +219,115 @@ func (b *builder) parameters(params *[]any) Builder {
 	return b
 }
 
+// Unscoped disables the named scopes for this builder, or all of them when no name is given.
+func (b *builder) Unscoped(scopes ...string) Builder {
+	b.unscoped = append([]string{}, scopes...)
+	return b
+}
+
+// scoped resolves the scopes for the next Build, including the joined tables and the builders of subqueries,
+// CTEs, compounds and conditions.
+func (b *builder) scoped(ctx context.Context) Builder {
+	b.scopes, b.scopeValues = nil, nil
+	checkRaw(b.table, true, b.unscoped)
+	if b.queryMode == modeSelect {
+		for _, field := range b.fields {
+			checkRaw(field, false, b.unscoped)
+		}
+	}
+	if b.table != "" {
+		alias := b.alias
+		if alias == "" && len(b.join) != 0 {
+			alias = mainTblAlias
+		}
+		for _, scope := range activeScopes(b.table, b.unscoped) {
+			if b.queryMode == modeInsert || b.queryMode == modeUpdate {
+				if scope.Values != nil {
+					if b.scopeValues == nil {
+						b.scopeValues = map[string]any{}
+					}
+					for field, value := range scope.Values(ctx) {
+						b.scopeValues[field] = value
+					}
+				}
+				if b.queryMode == modeInsert {
+					continue
+				}
+			}
+			if scope.Condition != nil {
+				if condition := scope.Condition(ctx, alias); condition != nil {
+					b.scopes = append(b.scopes, condition)
+				}
+			}
+		}
+	}
+
+	if b.subSelect != nil {
+		b.subSelect.scoped(ctx)
+	}
+	for _, c := range b.with {
+		c.builder.scoped(ctx)
+	}
+	for _, c := range b.compound {
+		c.builder.scoped(ctx)
+	}
+	for _, j := range b.join {
+		j.scopes = nil
+		checkRaw(j.table, true, b.unscoped)
+		checkRaw(j.on, false, b.unscoped)
+		if j.subquery != nil {
+			j.subquery.scoped(ctx)
+			continue
+		}
+		alias := j.alias
+		if alias == "" {
+			alias = j.table
+		}
+		for _, scope := range activeScopes(j.table, b.unscoped) {
+			if scope.Condition != nil {
+				if condition := scope.Condition(ctx, alias); condition != nil {
+					j.scopes = append(j.scopes, condition)
+				}
+			}
+		}
+	}
+	for _, e := range []Expression{b.where, b.having} {
+		if e != nil {
+			Walk(e, scopeVisitor{ctx})
+		}
+	}
+	return b
+}
+
+// scopeVisitor resolves the scopes of subqueries used as condition values.
+type scopeVisitor struct {
+	ctx context.Context
+}
+
+func (v scopeVisitor) Enter(string, bool) bool { return true }
+func (v scopeVisitor) Leave(string, bool)      {}
+
+func (v scopeVisitor) Condition(condition Condition) {
+	if sub, ok := condition.Value.(Builder); ok {
+		sub.scoped(v.ctx)
+	}
+}
+
 func (b *builder) Where(expression Expression) Builder {
 	b.where = expression
 	return b
 }
 
+func (b *builder) With(name string, builder Builder) Builder {
+	b.with = append(b.with, &cte{name: name, builder: builder})
+	return b
+}
+
+func (b *builder) WithRecursive(name string, builder Builder) Builder {
+	b.recursive = true
+	return b.With(name, builder)
+}
+
 func (b *builder) SubTable(subtable Builder) Builder {
 	switch b.queryMode {
 	case modeSelect:
@@ -173,6 +362,40 @@ func (b *builder) NotSort() Builder {
 	return b
 }
 
+func (b *builder) After(cursor *Cursor) Builder {
+	b.cursor = cursor
+	b.backward = false
+	return b
+}
+
+func (b *builder) Before(cursor *Cursor) Builder {
+	b.cursor = cursor
+	b.backward = true
+	return b
+}
+
+func (b *builder) Cursor(row any) *Cursor {
+	alias := b.alias
+	if alias == "" && len(b.join) != 0 {
+		alias = mainTblAlias
+	}
+	return cursorOf(keysetColumns(b.sorting(alias)), row)
+}
+
+func (b *builder) sorting(alias string) []string {
+	if len(b.sort) == 1 && b.sort[0] == doNotSort {
+		return nil
+	}
+	if len(b.sort) == 0 {
+		sortid := "id"
+		if len(b.join) != 0 && len(b.compound) == 0 {
+			sortid = fmt.Sprintf("%s.id", alias)
+		}
+		return []string{fmt.Sprintf("%s asc", sortid)}
+	}
+	return b.sort
+}
+
 func (b *builder) Window(window string) Builder {
 	b.window = window
 	return b
@@ -183,13 +406,38 @@ func (b *builder) Build() (string, *[]any) {
 	if b.params == nil {
 		b.params = pointer.Pointer([]any{})
 	}
+	defer func() {
+		b.params, b.scopes, b.scopeValues = nil, nil, nil
+		for _, j := range b.join {
+			j.scopes = nil
+		}
+	}()
+
+	with := ""
+	if len(b.with) > 0 {
+		ctes := make([]string, 0, len(b.with))
+		for _, c := range b.with {
+			subquery, _ := c.builder.parameters(b.params).Build()
+			ctes = append(ctes, fmt.Sprintf("%s as (%s)", c.name, subquery))
+		}
+		with = "with "
+		if b.recursive {
+			with = "with recursive "
+		}
+		with += strings.Join(ctes, ", ") + " "
+	}
+
+	alias := b.alias
+	if alias == "" && len(b.join) != 0 {
+		alias = mainTblAlias
+	}
 
 	switch b.queryMode {
 	case modeSelect:
 
 		asterics := "*"
 		if len(b.join) != 0 {
-			asterics = fmt.Sprintf("%s.*", mainTblAlias)
+			asterics = fmt.Sprintf("%s.*", alias)
 		}
 
 		if b.fields == nil || len(b.fields) == 0 {
@@ -212,33 +460,48 @@ func (b *builder) Build() (string, *[]any) {
 	}
 	if b.table != "" {
 		query = fmt.Sprintf("%s %s", query, b.table)
-		if len(b.join) != 0 {
-			query = fmt.Sprintf("%s AS %s", query, mainTblAlias)
+		if alias != "" {
+			query = fmt.Sprintf("%s AS %s", query, alias)
 		}
 	}
 	if b.queryMode == modeSelect && b.subSelect != nil {
-		subquery, params := b.subSelect.Build()
-		query = fmt.Sprintf("%s (%s) s", query, subquery)
-		*b.params = append(*b.params, *params...)
+		if alias == "" {
+			alias = "s"
+		}
+		subquery, _ := b.subSelect.parameters(b.params).Build()
+		query = fmt.Sprintf("%s (%s) %s", query, subquery, alias)
 	}
 
 	switch b.queryMode {
 	case modeInsert:
-		query = fmt.Sprintf("%s (%s)", query, strings.Join(b.fields, ", "))
+		fields, rows := b.fields, b.inserts
+		if b.structs != nil {
+			if b.namedMode {
+				if len(fields) == 0 {
+					fields, _ = structRows(nil, b.structs)
+				}
+			} else {
+				fields, rows = structRows(fields, b.structs)
+			}
+		}
+		if len(b.scopeValues) > 0 && (b.namedMode || b.subSelect != nil) {
+			panic(fmt.Errorf("scoped columns cannot be set on named or insert from select queries, use Unscoped to set them explicitly"))
+		}
+		fields, rows = b.scopeRows(fields, rows)
+		query = fmt.Sprintf("%s (%s)", query, strings.Join(fields, ", "))
 		var inserts []string
 		if b.namedMode {
-			for _, field := range b.fields {
+			for _, field := range fields {
 				inserts = append(inserts, ":"+field)
 			}
 			b.params = pointer.Pointer(b.inserts[0])
 			query = fmt.Sprintf("%s values (%s)", query, strings.Join(inserts, ", "))
 		} else if b.subSelect != nil {
-			subquery, params := b.subSelect.Build()
+			subquery, _ := b.subSelect.parameters(b.params).Build()
 			query = fmt.Sprintf("%s %s", query, subquery)
-			*b.params = append(*b.params, *params...)
 		} else {
-			for _, value := range b.inserts {
-				if len(value) != len(b.fields) {
+			for _, value := range rows {
+				if len(value) != len(fields) {
 					panic(fmt.Errorf("wrong insert value (count of fields not match count of values)"))
 				}
 				var line []string
@@ -256,12 +519,20 @@ func (b *builder) Build() (string, *[]any) {
 		}
 	case modeUpdate:
 		var updates []string
-		for field, value := range b.updates {
-			if r, ok := value.(*raw); ok {
-				updates = append(updates, fmt.Sprintf("%s=%s", field, r.expression))
+		assignments := b.updates
+		if b.version != nil {
+			assignments = list.Filter(assignments, func(update *assignment) bool { return update.field != b.version.field })
+			assignments = append(assignments, &assignment{field: b.version.field, value: Raw(b.version.field + " + 1")})
+		}
+		for _, update := range assignments {
+			if value, ok := b.scopeValues[update.field]; ok {
+				update = &assignment{field: update.field, value: value}
+			}
+			if r, ok := update.value.(*raw); ok {
+				updates = append(updates, fmt.Sprintf("%s=%s", update.field, r.expression))
 			} else {
-				*b.params = append(*b.params, value)
-				updates = append(updates, fmt.Sprintf("%s=$%d", field, len(*b.params)))
+				*b.params = append(*b.params, update.value)
+				updates = append(updates, fmt.Sprintf("%s=$%d", update.field, len(*b.params)))
 			}
 		}
 		query = fmt.Sprintf("%s set %s", query, strings.Join(updates, ", "))
@@ -269,14 +540,63 @@ func (b *builder) Build() (string, *[]any) {
 
 	if len(b.join) > 0 && b.queryMode == modeSelect {
 		for _, j := range b.join {
-			appendJoin := fmt.Sprintf("%s JOIN %s AS %s ON %s", j.mode, j.table, j.alias, j.on)
+			table := j.table
+			if j.subquery != nil {
+				subquery, _ := j.subquery.parameters(b.params).Build()
+				table = fmt.Sprintf("(%s)", subquery)
+			}
+			on := j.on
+			if j.condition != nil {
+				if on, _ = j.condition.query(b.params); on != "" && len(j.scopes) > 0 {
+					on = enclose(j.condition, on)
+				}
+			} else if on != "" && len(j.scopes) > 0 {
+				on = fmt.Sprintf("(%s)", on)
+			}
+			conditions := list.Filter([]string{on}, func(condition string) bool { return condition != "" })
+			for _, scope := range j.scopes {
+				if condition, _ := scope.query(b.params); condition != "" {
+					conditions = append(conditions, condition)
+				}
+			}
+			on = strings.Join(conditions, " and ")
+			if on == "" {
+				on = "true"
+			}
+			appendJoin := fmt.Sprintf("%s JOIN %s AS %s ON %s", j.mode, table, j.alias, on)
 			query = fmt.Sprintf("%s %s", query, appendJoin)
 		}
 	}
 
+	var conditions []string
+	where := ""
 	if b.where != nil && b.queryMode != modeInsert {
-		where, _ := b.where.query(b.params)
-		query = fmt.Sprintf("%s where %s", query, where)
+		if where, _ = b.where.query(b.params); where != "" {
+			conditions = append(conditions, where)
+		}
+	}
+	for _, scope := range b.scopes {
+		if condition, _ := scope.query(b.params); condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	if b.queryMode == modeUpdate && b.version != nil {
+		condition, _ := And(b.version.field, "eq", b.version.value).query(b.params)
+		conditions = append(conditions, condition)
+	}
+	var keyset []keysetColumn
+	if b.queryMode == modeSelect && b.cursor != nil {
+		if len(b.compound) > 0 {
+			panic(fmt.Errorf("keyset pagination is not supported for compound queries"))
+		}
+		keyset = keysetColumns(b.sorting(alias))
+		conditions = append(conditions, keysetCondition(keyset, b.cursor, b.backward, b.params))
+	}
+	if len(conditions) > 0 {
+		if where != "" && len(conditions) > 1 {
+			conditions[0] = enclose(b.where, where)
+		}
+		query = fmt.Sprintf("%s where %s", query, strings.Join(conditions, " and "))
 	}
 
 	if b.window != "" {
@@ -287,22 +607,31 @@ func (b *builder) Build() (string, *[]any) {
 		query = fmt.Sprintf("%s group by %s", query, strings.Join(b.group, ","))
 	}
 
+	if b.queryMode == modeSelect && b.having != nil {
+		if having, _ := b.having.query(b.params); having != "" {
+			query = fmt.Sprintf("%s having %s", query, having)
+		}
+	}
+
+	if b.queryMode == modeSelect && len(b.compound) > 0 {
+		query = fmt.Sprintf("(%s)", query)
+		for _, c := range b.compound {
+			subquery, _ := c.builder.parameters(b.params).Build()
+			query = fmt.Sprintf("%s %s (%s)", query, c.operation, subquery)
+		}
+	}
+
 	if b.queryMode == modeSelect {
-		if len(b.sort) == 1 && b.sort[0] == doNotSort {
-			//do nothing with query
-		} else {
-			if b.sort == nil || len(b.sort) == 0 {
-				sortid := "id"
-				if len(b.join) != 0 {
-					sortid = fmt.Sprintf("%s.id", mainTblAlias)
-				}
-				b.sort = []string{fmt.Sprintf("%s asc", sortid)}
-			}
-			query = fmt.Sprintf("%s order by %s", query, strings.Join(b.sort, ","))
+		sort := b.sorting(alias)
+		if keyset != nil && b.backward {
+			sort = keysetSort(keyset, true, false)
+		}
+		if len(sort) > 0 {
+			query = fmt.Sprintf("%s order by %s", query, strings.Join(sort, ","))
 		}
 	}
 
-	if b.page != 0 {
+	if b.page != 0 && keyset == nil {
 		offset := b.page
 		if b.count != 0 {
 			offset *= b.count
@@ -313,6 +642,14 @@ func (b *builder) Build() (string, *[]any) {
 		query = fmt.Sprintf("%s limit %d", query, b.count)
 	}
 
+	if b.queryMode == modeSelect && b.lock != "" {
+		query = fmt.Sprintf("%s for %s", query, b.lock)
+	}
+
+	if keyset != nil && b.backward {
+		query = fmt.Sprintf("select * from (%s) k order by %s", query, strings.Join(keysetSort(keyset, false, true), ","))
+	}
+
 	if b.queryMode != modeSelect && b.conflictKey != "" {
 		if b.conflictFields == nil || len(b.conflictFields) == 0 {
 			query = fmt.Sprintf("%s on conflict (%s) do nothing", query, b.conflictKey)
@@ -326,24 +663,65 @@ func (b *builder) Build() (string, *[]any) {
 		query = fmt.Sprintf("%s returning %s", query, strings.Join(b.returning, ","))
 	}
 
-	return query, b.params
+	return with + query, b.params
+}
+
+// scopeRows sets the scope values on every row: explicitly listed scoped columns are overwritten, the others
+// are appended.
+func (b *builder) scopeRows(fields []string, rows [][]any) ([]string, [][]any) {
+	if len(b.scopeValues) == 0 {
+		return fields, rows
+	}
+	columns := list.Keys(b.scopeValues)
+	sort.Strings(columns)
+	columns = list.Filter(columns, func(column string) bool { return !list.Contains(column, fields) })
+	fields = append(append([]string{}, fields...), columns...)
+	scoped := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		row = append([]any{}, row...)
+		for _, column := range columns {
+			row = append(row, b.scopeValues[column])
+		}
+		for idx, field := range fields {
+			if value, ok := b.scopeValues[field]; ok && idx < len(row) {
+				row[idx] = value
+			}
+		}
+		scoped = append(scoped, row)
+	}
+	return fields, scoped
 }
 
 func (b *builder) Values(values ...any) InsertBuilder {
-	// 0 — uninitialized; 1 — [][]any; 2 — []any
+	// 0 — uninitialized; 1 — [][]any; 2 — []any; 3 — structs
 	mode := 0
 	var inserts [][]any
+	var structs []any
 	for _, value := range values {
-		if value != nil && reflect.TypeOf(value).Kind() == reflect.Slice {
-			if mode != 0 && mode != 1 {
+		if isStructRow(value) {
+			if mode != 0 && mode != 3 {
 				panic(fmt.Errorf("cannot mix values, structs and slices"))
 			}
-			mode = 1
+			mode = 3
+			structs = append(structs, value)
+		} else if value != nil && reflect.TypeOf(value).Kind() == reflect.Slice {
 			v := reflect.ValueOf(value)
 			var item []any
 			for i := 0; i < v.Len(); i++ {
 				item = append(item, v.Index(i).Interface())
 			}
+			if isStructType(v.Type().Elem()) {
+				if mode != 0 && mode != 3 {
+					panic(fmt.Errorf("cannot mix values, structs and slices"))
+				}
+				mode = 3
+				structs = append(structs, item...)
+				continue
+			}
+			if mode != 0 && mode != 1 {
+				panic(fmt.Errorf("cannot mix values, structs and slices"))
+			}
+			mode = 1
 			inserts = append(inserts, item)
 		} else {
 			if mode != 0 && mode != 2 {
@@ -355,7 +733,11 @@ func (b *builder) Values(values ...any) InsertBuilder {
 	if mode == 2 {
 		inserts = append(inserts, values)
 	}
+	if mode == 3 {
+		inserts = [][]any{structs}
+	}
 	b.inserts = inserts
+	b.structs = structs
 	return b
 }
 
@@ -365,6 +747,11 @@ func (b *builder) Conflict(conflict string, excluded ...string) InsertBuilder {
 	return b
 }
 
+func (b *builder) As(alias string) SelectBuilder {
+	b.alias = alias
+	return b
+}
+
 func (b *builder) Join(mode string, table string, alias string, on string) SelectBuilder {
 
 	j := &join{
@@ -376,3 +763,56 @@ func (b *builder) Join(mode string, table string, alias string, on string) Selec
 	b.join = append(b.join, j)
 	return b
 }
+
+func (b *builder) JoinOn(mode string, table string, alias string, on Expression) SelectBuilder {
+	b.join = append(b.join, &join{mode: mode, table: table, alias: alias, condition: on})
+	return b
+}
+
+func (b *builder) JoinSub(mode string, subquery Builder, alias string, on Expression) SelectBuilder {
+	b.join = append(b.join, &join{mode: mode, subquery: subquery, alias: alias, condition: on})
+	return b
+}
+
+func (b *builder) Having(expression Expression) SelectBuilder {
+	b.having = expression
+	return b
+}
+
+func (b *builder) Lock(clause string) SelectBuilder {
+	b.lock = clause
+	return b
+}
+
+func (b *builder) Union(other Builder) SelectBuilder {
+	return b.combine(operationUnion, other)
+}
+
+func (b *builder) UnionAll(other Builder) SelectBuilder {
+	return b.combine(operationUnionAll, other)
+}
+
+func (b *builder) Intersect(other Builder) SelectBuilder {
+	return b.combine(operationIntersect, other)
+}
+
+func (b *builder) Except(other Builder) SelectBuilder {
+	return b.combine(operationExcept, other)
+}
+
+func (b *builder) combine(operation string, other Builder) SelectBuilder {
+	if b.queryMode != modeSelect {
+		panic(fmt.Errorf("%s is supported only for select queries", operation))
+	}
+	b.compound = append(b.compound, &compound{operation: operation, builder: other})
+	return b
+}
+
+// enclose parenthesizes the rendered expression when it joins conditions with or, so that conditions appended
+// with and restrict all of them.
+func enclose(e Expression, rendered string) string {
+	if x, ok := e.(*expression); ok && x.strategy == strategyOr && len(x.parts) > 1 {
+		return fmt.Sprintf("(%s)", rendered)
+	}
+	return rendered
+}
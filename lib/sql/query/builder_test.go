@@ -3,6 +3,7 @@ package query
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestBuilder(t *testing.T) {
@@ -70,6 +71,10 @@ func TestBuilder(t *testing.T) {
 			assert.Equal(t, "select f,s from test where (f = $1) order by id asc", q)
 			assert.Equal(t, &[]any{7}, params)
 
+			q, params = NewBuilder("test").Select("id").Lock("update skip locked").Where(And("f", "eq", 7)).Page(0, 5).Build()
+			assert.Equal(t, "select id from test where (f = $1) order by id asc limit 5 for update skip locked", q)
+			assert.Equal(t, &[]any{7}, params)
+
 			b = NewBuilder("test")
 			q, params = b.Where(And("f", "eq", 7)).NotSort().Build()
 			assert.Equal(t, "select * from test where (f = $1)", q)
@@ -91,20 +96,81 @@ func TestBuilder(t *testing.T) {
 			assert.Equal(t, "select id as ma, ot.id as ba from test AS maintbl LEFT OUTER JOIN other_table AS ot ON ot.id = mainTbl.id LEFT OUTER JOIN other_table2 AS ot2 ON ot.id = ot2.id order by maintbl.id asc", q)
 			assert.Empty(t, params)
 
+			q, params = NewBuilder("test").Select().As("t").
+				JoinOn("INNER", "other_table", "ot", And("ot.test_id", "eq", Column("t.id")).Add("ot.kind", "eq", 3)).
+				JoinSub("LEFT", NewBuilder("stats").Select("test_id", "count(*) as cnt").Where(And("day", "ge", "2024-01-01")).Group("test_id").NotSort(), "st", And("st.test_id", "eq", Column("t.id"))).
+				Where(And("t.active", "eq", true)).
+				Build()
+			assert.Equal(t, "select t.* from test AS t INNER JOIN other_table AS ot ON (ot.test_id = t.id) and (ot.kind = $1) LEFT JOIN (select test_id,count(*) as cnt from stats where (day >= $2) group by test_id) AS st ON (st.test_id = t.id) where (t.active = $3) order by t.id asc", q)
+			assert.Equal(t, &[]any{3, "2024-01-01", true}, params)
+
+			q, params = NewBuilder().Select("s2.kind", "count(*)").As("s2").
+				Having(And("count(*)", "gt", 5)).
+				SubTable(NewBuilder("test").Where(And("a", "eq", 1)).NotSort()).
+				Where(And("b", "ne", 2)).
+				Group("s2.kind").
+				NotSort().
+				Build()
+			assert.Equal(t, "select s2.kind,count(*) from (select * from test where (a = $1)) s2 where (b != $2) group by s2.kind having (count(*) > $3)", q)
+			assert.Equal(t, &[]any{1, 2, 5}, params)
+
 		},
 	)
 
+	type base struct {
+		Id      int       `db:"id,readonly"`
+		Created time.Time `db:"created_at,omitempty"`
+	}
+	type user struct {
+		base
+		Name    string `db:"name"`
+		Email   string `db:"email,omitempty"`
+		Age     int
+		Ignored string `db:"-"`
+		secret  string
+	}
+
 	t.Run(
 		"Insert",
 		func(t *testing.T) {
+			q, params := NewBuilder("users").Insert().Values(user{Name: "a", Age: 3}, &user{Name: "b", Email: "b@x"}).Build()
+			assert.Equal(t, "insert into users (name, email, age) values ($1, default, $2), ($3, $4, $5)", q)
+			assert.Equal(t, &[]any{"a", 3, "b", "b@x", 0}, params)
+
+			q, params = NewBuilder("users").Insert("age", "name").Values([]user{{Name: "a", Age: 3}}).Conflict("name", "age").Build()
+			assert.Equal(t, "insert into users (age, name) values ($1, $2) on conflict (name) do update set age=excluded.age", q)
+			assert.Equal(t, &[]any{3, "a"}, params)
+
+			q, params = NewBuilder("users").Insert("author", "age").Values([]any{user{Name: "a"}, 3}).Build()
+			assert.Equal(t, "insert into users (author, age) values ($1, $2)", q)
+			assert.Equal(t, &[]any{user{Name: "a"}, 3}, params)
 
+			fields, rows := Rows(nil, []user{{Name: "a"}, {Name: "b", Email: "c"}})
+			assert.Equal(t, []string{"name", "email", "age"}, fields)
+			assert.Equal(t, [][]any{{"a", Raw("default"), 0}, {"b", "c", 0}}, rows)
+
+			assert.Equal(t, 5, FieldValue(&user{base: base{Id: 5}}, "id"))
+			assert.PanicsWithError(t, "field 'missing' not found in query.user", func() { FieldValue(user{}, "missing") })
+
+			fields, rows = Rows([]string{"a", "b"}, []any{1, 2}, []any{3, 4})
+			assert.Equal(t, []string{"a", "b"}, fields)
+			assert.Equal(t, [][]any{{1, 2}, {3, 4}}, rows)
+
+			assert.PanicsWithError(t, "cannot mix values, structs and slices", func() { NewBuilder("users").Insert().Values(user{}, []any{1}) })
+			assert.PanicsWithError(t, "field 'missing' not found in query.user", func() { NewBuilder("users").Insert("missing").Values(user{}).Build() })
 		},
 	)
 
 	t.Run(
 		"Update",
 		func(t *testing.T) {
+			q, params := NewBuilder("users").Update(map[string]any{"z": 1, "b": Raw("now()"), "a": "x", "m": nil}).Where(And("id", "eq", 7)).Build()
+			assert.Equal(t, "update users set a=$1, b=now(), m=$2, z=$3 where (id = $4)", q)
+			assert.Equal(t, &[]any{"x", nil, 1, 7}, params)
 
+			q, params = NewBuilder("users").UpdateStruct(&user{base: base{Id: 7}, Name: "a"}).Where(And("id", "eq", 7)).Build()
+			assert.Equal(t, "update users set name=$1, age=$2 where (id = $3)", q)
+			assert.Equal(t, &[]any{"a", 0, 7}, params)
 		},
 	)
 
@@ -129,4 +195,79 @@ func TestBuilder(t *testing.T) {
 		},
 	)
 
+	t.Run(
+		"With",
+		func(t *testing.T) {
+			active := NewBuilder("users").Select("id").Where(And("status", "eq", "active")).NotSort()
+			q, params := NewBuilder("orders join active_users on active_users.id = orders.user_id").
+				Select("user_id", "sum(total)").
+				With("active_users", active).
+				Where(And("total", "gt", 10)).
+				Group("user_id").
+				NotSort().
+				Build()
+			assert.Equal(t, "with active_users as (select id from users where (status = $1)) select user_id,sum(total) from orders join active_users on active_users.id = orders.user_id where (total > $2) group by user_id", q)
+			assert.Equal(t, &[]any{"active", 10}, params)
+
+			q, params = NewBuilder("archive").Insert("id").
+				With("old", NewBuilder("orders").Delete().Where(And("created", "lt", 2020)).Returning("id")).
+				SubTable(NewBuilder("old").Select("id").NotSort()).
+				Build()
+			assert.Equal(t, "with old as (delete from orders where (created < $1) returning id) insert into archive (id) select id from old", q)
+			assert.Equal(t, &[]any{2020}, params)
+
+			q, params = NewBuilder("tree").
+				Update(map[string]any{"checked": true}).
+				WithRecursive("tree(id, parent_id)", NewBuilder("nodes").Select("id", "parent_id").Where(And("id", "eq", 1)).NotSort()).
+				With("names", NewBuilder("labels").Where(And("lang", "eq", "en")).NotSort()).
+				Where(And("id", "ne", 5)).
+				Build()
+			assert.Equal(t, "with recursive tree(id, parent_id) as (select id,parent_id from nodes where (id = $1)), names as (select * from labels where (lang = $2)) update tree set checked=$3 where (id != $4)", q)
+			assert.Equal(t, &[]any{1, "en", true, 5}, params)
+
+			b := NewBuilder("test").With("c", NewBuilder("other").Where(And("a", "eq", 1)).NotSort())
+			first, firstParams := b.Build()
+			second, secondParams := b.Build()
+			assert.Equal(t, first, second)
+			assert.Equal(t, firstParams, secondParams)
+		},
+	)
+	t.Run(
+		"Compound",
+		func(t *testing.T) {
+			q, params := NewBuilder("a").Select("id", "name").
+				Union(NewBuilder("b").Select("id", "name").Where(And("kind", "eq", 2)).NotSort()).
+				Where(And("kind", "eq", 1)).
+				Sort("name desc").
+				Page(1, 10).
+				Build()
+			assert.Equal(t, "(select id,name from a where (kind = $1)) union (select id,name from b where (kind = $2)) order by name desc offset 10 limit 10", q)
+			assert.Equal(t, &[]any{1, 2}, params)
+
+			q, params = NewBuilder("a").Select().
+				UnionAll(NewBuilder("b").Where(And("x", "gt", 1)).NotSort()).
+				Intersect(NewBuilder("c").NotSort()).
+				Except(NewBuilder("d").Where(And("x", "eq", []int{4, 5})).Page(0, 1)).
+				Build()
+			assert.Equal(t, "(select * from a) union all (select * from b where (x > $1)) intersect (select * from c) except (select * from d where (x in ($2,$3)) order by id asc limit 1) order by id asc", q)
+			assert.Equal(t, &[]any{1, 4, 5}, params)
+
+			q, params = NewBuilder("tree").
+				WithRecursive(
+					"tree",
+					NewBuilder("nodes").Select("id", "parent_id").
+						UnionAll(NewBuilder("nodes n join tree t on n.parent_id = t.id").Select("n.id", "n.parent_id").NotSort()).
+						Where(And("id", "eq", 7)).
+						NotSort(),
+				).
+				NotSort().
+				Build()
+			assert.Equal(t, "with recursive tree as ((select id,parent_id from nodes where (id = $1)) union all (select n.id,n.parent_id from nodes n join tree t on n.parent_id = t.id)) select * from tree", q)
+			assert.Equal(t, &[]any{7}, params)
+
+			assert.PanicsWithError(t, "union is supported only for select queries", func() {
+				NewBuilder("a").Delete().(SelectBuilder).Union(NewBuilder("b"))
+			})
+		},
+	)
 }
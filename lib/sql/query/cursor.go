@@ -0,0 +1,192 @@
+package query
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/betam/glb/lib/try"
+)
+
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+type Cursor struct {
+	values []any
+}
+
+func NewCursor(values ...any) *Cursor {
+	return &Cursor{values: values}
+}
+
+func DecodeCursor(token string) *Cursor {
+	c := &Cursor{}
+	try.Catch(
+		func() {
+			data := try.Throw(base64.RawURLEncoding.DecodeString(token))
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			try.ThrowError(decoder.Decode(&c.values))
+			if len(c.values) == 0 {
+				panic(fmt.Errorf("empty cursor"))
+			}
+		},
+		func(throwable error) {
+			panic(fmt.Errorf("%w: %v", ErrInvalidCursor, throwable))
+		},
+	)
+	return c
+}
+
+func (c *Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString(try.Throw(json.Marshal(c.values)))
+}
+
+func (c *Cursor) Values() []any {
+	return c.values
+}
+
+func (c *Cursor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *Cursor) UnmarshalJSON(value []byte) error {
+	var token string
+	if err := json.Unmarshal(value, &token); err != nil {
+		return err
+	}
+	var err error
+	try.Catch(
+		func() { *c = *DecodeCursor(token) },
+		func(throwable error) { err = throwable },
+	)
+	return err
+}
+
+type keysetColumn struct {
+	field string
+	desc  bool
+}
+
+func keysetColumns(sort []string) []keysetColumn {
+	if len(sort) == 0 {
+		panic(fmt.Errorf("keyset pagination requires sort columns"))
+	}
+	columns := make([]keysetColumn, 0, len(sort))
+	for _, s := range sort {
+		parts := strings.Fields(s)
+		column := keysetColumn{field: parts[0]}
+		switch {
+		case len(parts) == 1:
+		case len(parts) == 2 && strings.EqualFold(parts[1], "asc"):
+		case len(parts) == 2 && strings.EqualFold(parts[1], "desc"):
+			column.desc = true
+		default:
+			panic(fmt.Errorf("unsupported sort for keyset pagination: '%s'", s))
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func keysetCondition(columns []keysetColumn, cursor *Cursor, backward bool, params *[]any) string {
+	if len(cursor.values) != len(columns) {
+		panic(fmt.Errorf("%w: expected %d values given %d", ErrInvalidCursor, len(columns), len(cursor.values)))
+	}
+	operator := func(column keysetColumn) string {
+		if column.desc != backward {
+			return "<"
+		}
+		return ">"
+	}
+
+	uniform := true
+	for _, column := range columns {
+		uniform = uniform && column.desc == columns[0].desc
+	}
+	if uniform {
+		fields := make([]string, 0, len(columns))
+		values := make([]string, 0, len(columns))
+		for idx, column := range columns {
+			*params = append(*params, cursor.values[idx])
+			fields = append(fields, column.field)
+			values = append(values, fmt.Sprintf("$%d", len(*params)))
+		}
+		if len(columns) == 1 {
+			return fmt.Sprintf("(%s %s %s)", fields[0], operator(columns[0]), values[0])
+		}
+		return fmt.Sprintf("((%s) %s (%s))", strings.Join(fields, ", "), operator(columns[0]), strings.Join(values, ", "))
+	}
+
+	placeholders := make([]string, 0, len(columns))
+	for idx := range columns {
+		*params = append(*params, cursor.values[idx])
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(*params)))
+	}
+	var alternatives []string
+	for idx, column := range columns {
+		var parts []string
+		for prev := 0; prev < idx; prev++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", columns[prev].field, placeholders[prev]))
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", column.field, operator(column), placeholders[idx]))
+		alternatives = append(alternatives, fmt.Sprintf("(%s)", strings.Join(parts, " and ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(alternatives, " or "))
+}
+
+func keysetSort(columns []keysetColumn, reverse bool, unqualified bool) []string {
+	sort := make([]string, 0, len(columns))
+	for _, column := range columns {
+		field := column.field
+		if unqualified {
+			field = columnName(field)
+		}
+		direction := "asc"
+		if column.desc != reverse {
+			direction = "desc"
+		}
+		sort = append(sort, fmt.Sprintf("%s %s", field, direction))
+	}
+	return sort
+}
+
+func columnName(field string) string {
+	return field[strings.LastIndex(field, ".")+1:]
+}
+
+func cursorOf(columns []keysetColumn, row any) *Cursor {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			panic(fmt.Errorf("cannot build cursor from nil row"))
+		}
+		v = v.Elem()
+	}
+
+	values := make([]any, 0, len(columns))
+	for _, column := range columns {
+		name := columnName(column.field)
+		var value reflect.Value
+		switch v.Kind() {
+		case reflect.Map:
+			value = v.MapIndex(reflect.ValueOf(name))
+		case reflect.Struct:
+			value = structFieldByColumn(v, name)
+		default:
+			panic(fmt.Errorf("cannot build cursor from %s", v.Type()))
+		}
+		if !value.IsValid() {
+			panic(fmt.Errorf("cannot build cursor: field '%s' not found", name))
+		}
+		item := value.Interface()
+		if valuer, ok := item.(driver.Valuer); ok && (value.Kind() != reflect.Ptr || !value.IsNil()) {
+			item = try.Throw(valuer.Value())
+		}
+		values = append(values, item)
+	}
+	return NewCursor(values...)
+}
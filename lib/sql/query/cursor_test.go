@@ -0,0 +1,93 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/try"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run(
+		"After",
+		func(t *testing.T) {
+			q, params := NewBuilder("test").Where(And("f", "eq", 7)).After(NewCursor(10)).Page(0, 20).Build()
+			assert.Equal(t, "select * from test where (f = $1) and (id > $2) order by id asc limit 20", q)
+			assert.Equal(t, &[]any{7, 10}, params)
+
+			q, params = NewBuilder("test").Sort("created desc", "id desc").After(NewCursor("2024-01-01", 10)).Page(0, 20).Build()
+			assert.Equal(t, "select * from test where ((created, id) < ($1, $2)) order by created desc,id desc limit 20", q)
+			assert.Equal(t, &[]any{"2024-01-01", 10}, params)
+
+			q, params = NewBuilder("test").Sort("name asc", "id desc").After(NewCursor("a", 10)).Build()
+			assert.Equal(t, "select * from test where ((name > $1) or (name = $1 and id < $2)) order by name asc,id desc", q)
+			assert.Equal(t, &[]any{"a", 10}, params)
+
+			q, _ = NewBuilder("test").After(NewCursor(10)).Page(3, 20).Build()
+			assert.Equal(t, "select * from test where (id > $1) order by id asc limit 20", q)
+
+			q, params = NewBuilder("test").Where(Or(And("a", "eq", 1), And("b", "eq", 2))).After(NewCursor(10)).Build()
+			assert.Equal(t, "select * from test where (((a = $1)) or ((b = $2))) and (id > $3) order by id asc", q)
+			assert.Equal(t, &[]any{1, 2, 10}, params)
+		},
+	)
+
+	t.Run(
+		"Before",
+		func(t *testing.T) {
+			q, params := NewBuilder("test").Select().As("t").Sort("t.name asc", "t.id asc").Before(NewCursor("b", 5)).Page(0, 2).Build()
+			assert.Equal(t, "select * from (select * from test AS t where ((t.name, t.id) < ($1, $2)) order by t.name desc,t.id desc limit 2) k order by name asc,id asc", q)
+			assert.Equal(t, &[]any{"b", 5}, params)
+		},
+	)
+
+	t.Run(
+		"FromRow",
+		func(t *testing.T) {
+			type row struct {
+				Id      int    `db:"id"`
+				Name    string `db:"name"`
+				Comment string
+			}
+			b := NewBuilder("test").Sort("t.name asc", "id asc")
+			cursor := b.Cursor(&row{Id: 3, Name: "c"})
+			assert.Equal(t, []any{"c", 3}, cursor.Values())
+			assert.Equal(t, []any{"c", 3}, b.Cursor(map[string]any{"id": 3, "name": "c"}).Values())
+			assert.PanicsWithError(t, "cannot build cursor: field 'missing' not found", func() { NewBuilder("test").Sort("missing").Cursor(row{}) })
+
+			decoded := DecodeCursor(cursor.String())
+			assert.Equal(t, []any{"c", json.Number("3")}, decoded.Values())
+		},
+	)
+
+	t.Run(
+		"Json",
+		func(t *testing.T) {
+			payload := struct {
+				Next *Cursor `json:"next"`
+			}{Next: NewCursor(1, "a")}
+			data := try.Throw(json.Marshal(payload))
+			assert.Equal(t, `{"next":"WzEsImEiXQ"}`, string(data))
+
+			payload.Next = nil
+			try.ThrowError(json.Unmarshal(data, &payload))
+			assert.Equal(t, []any{json.Number("1"), "a"}, payload.Next.Values())
+
+			err := json.Unmarshal([]byte(`{"next":"!!"}`), &payload)
+			assert.True(t, errors.Is(err, ErrInvalidCursor))
+		},
+	)
+
+	t.Run(
+		"Errors",
+		func(t *testing.T) {
+			assert.PanicsWithError(t, "invalid cursor: expected 1 values given 2", func() { NewBuilder("test").After(NewCursor(1, 2)).Build() })
+			assert.PanicsWithError(t, "keyset pagination requires sort columns", func() { NewBuilder("test").NotSort().After(NewCursor(1)).Build() })
+			assert.PanicsWithError(t, "unsupported sort for keyset pagination: 'id asc nulls last'", func() { NewBuilder("test").Sort("id asc nulls last").After(NewCursor(1)).Build() })
+			assert.PanicsWithError(t, "invalid cursor: empty cursor", func() { DecodeCursor("W10") })
+		},
+	)
+}
@@ -0,0 +1,209 @@
+package query
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/betam/glb/lib/list"
+)
+
+// Dialect renders parameter values as SQL literals.
+type Dialect interface {
+	Literal(value any) string
+}
+
+var Postgres Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) Literal(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case secret:
+		return quote(Redacted)
+	case *raw:
+		return v.expression
+	case driver.Valuer:
+		converted, err := v.Value()
+		if err != nil {
+			return fmt.Sprintf("/* %v */ NULL", err)
+		}
+		return Postgres.Literal(converted)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return quote(v)
+	case []byte:
+		return fmt.Sprintf(`'\x%s'::bytea`, hex.EncodeToString(v))
+	case time.Time:
+		return fmt.Sprintf("%s::timestamptz", quote(v.Format(time.RFC3339Nano)))
+	default:
+		return quote(fmt.Sprintf("%v", v))
+	}
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Debug builds the query with the parameters inlined as literals of the dialect (Postgres by default). The result
+// is meant for logs and test messages, not for execution.
+func Debug(builder Builder, dialect ...Dialect) string {
+	if len(dialect) > 1 {
+		panic(fmt.Errorf("unexpected argument count: expected 0 or 1 dialect given %d", len(dialect)))
+	}
+	d := Postgres
+	if len(dialect) == 1 {
+		d = dialect[0]
+	}
+	query, args := builder.Build()
+	return Inline(query, *args, d)
+}
+
+// Inline replaces $N placeholders outside of literals, quoted identifiers and comments with the parameters.
+func Inline(query string, args []any, dialect Dialect) string {
+	var result strings.Builder
+	scanSql(query, func(chunk string, code bool) {
+		if !code || chunk[0] != '$' {
+			result.WriteString(chunk)
+			return
+		}
+		idx, err := strconv.Atoi(chunk[1:])
+		if err != nil || idx < 1 || idx > len(args) {
+			result.WriteString(chunk)
+			return
+		}
+		result.WriteString(dialect.Literal(args[idx-1]))
+	})
+	return result.String()
+}
+
+var (
+	prettyClauses = []string{
+		"with", "select", "from", "where", "group", "having", "window", "order", "limit", "offset",
+		"left", "right", "full", "inner", "cross", "join", "union", "intersect", "except",
+		"insert", "values", "update", "set", "delete", "returning", "for",
+	}
+	prettyModifiers = []string{"left", "right", "full", "inner", "cross", "outer", "natural", "distinct", "delete", "do", "for", "no", "key"}
+)
+
+// Pretty puts every clause of the query on its own line, indenting subqueries by their nesting level.
+func Pretty(query string) string {
+	var result strings.Builder
+	depth, spaces, previous := 0, "", ""
+	scanSql(query, func(chunk string, code bool) {
+		if code && strings.TrimSpace(chunk) == "" {
+			spaces += chunk
+			return
+		}
+		word := strings.ToLower(chunk)
+		if code && result.Len() > 0 && list.Contains(word, prettyClauses) && !list.Contains(previous, prettyModifiers) {
+			spaces = "\n" + strings.Repeat("  ", depth)
+		}
+		result.WriteString(spaces)
+		result.WriteString(chunk)
+		spaces = ""
+		switch chunk {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if code {
+			previous = word
+		}
+	})
+	return result.String()
+}
+
+// scanSql splits the query into chunks: words, placeholders, single characters and, with code=false, string
+// literals, quoted identifiers, dollar-quoted strings and comments.
+func scanSql(query string, visit func(chunk string, code bool)) {
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+			visit(query[i:end], false)
+			i = end
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			visit(query[i:i+end], false)
+			i += end
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end = i + 2 + end + 2
+			}
+			visit(query[i:end], false)
+			i = end
+		case c == '$':
+			end := i + 1
+			for end < len(query) && isWordByte(query[end]) {
+				end++
+			}
+			if end < len(query) && query[end] == '$' && (end == i+1 || !isDigit(query[i+1])) {
+				tag := query[i : end+1]
+				closing := strings.Index(query[end+1:], tag)
+				if closing < 0 {
+					closing = len(query)
+				} else {
+					closing = end + 1 + closing + len(tag)
+				}
+				visit(query[i:closing], false)
+				i = closing
+				continue
+			}
+			visit(query[i:end], true)
+			i = end
+		case isWordByte(c):
+			end := i + 1
+			for end < len(query) && isWordByte(query[end]) {
+				end++
+			}
+			visit(query[i:end], true)
+			i = end
+		default:
+			visit(query[i:i+1], true)
+			i++
+		}
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
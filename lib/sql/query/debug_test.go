@@ -0,0 +1,44 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug(t *testing.T) {
+	t.Run(
+		"Literals",
+		func(t *testing.T) {
+			moment := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			builder := NewBuilder("users").Select("id").Where(
+				And("name", "eq", "o'neil").
+					Add("age", "between", []any{18, 30.5}).
+					Add("active", "eq", true).
+					Add("created", "lt", moment).
+					Add("password", "eq", Secret("password")).
+					Add("deleted", "eq", nil),
+			).NotSort()
+			assert.Equal(
+				t,
+				"select id from users where (name = 'o''neil') and (age between 18 and 30.5) and (active = TRUE) and (created < '2024-01-02T03:04:05Z'::timestamptz) and (password = '[REDACTED]') and (deleted is null)",
+				Debug(builder),
+			)
+			assert.Equal(t, `select $1, '$1', "$1", $tag$ $1 $tag$ -- $1`, Inline(`select $1, '$1', "$1", $tag$ $1 $tag$ -- $1`, nil, Postgres))
+			assert.Equal(t, `select '\x0102'::bytea, 'it''s $2', NULL`, Inline(`select $1, 'it''s $2', $3`, []any{[]byte{1, 2}, "unused", nil}, Postgres))
+			assert.Panics(t, func() { Debug(builder, Postgres, Postgres) })
+		},
+	)
+
+	t.Run(
+		"Pretty",
+		func(t *testing.T) {
+			assert.Equal(
+				t,
+				"select *\nfrom a\nleft join b on a.id = b.id\nwhere a.x is not distinct from 'from where' and a.id in (\n  select id\n  from c)\norder by a.z\nfor no key update",
+				Pretty("select * from a left join b on a.id = b.id where a.x is not distinct from 'from where' and a.id in (select id from c) order by a.z for no key update"),
+			)
+		},
+	)
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/betam/glb/lib/try"
+)
+
+// ErrStop ends an iteration early when returned by the Each callback.
+var ErrStop = fmt.Errorf("stop iteration")
+
+type rowsHandler func(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
+
+// Each runs the query and passes the rows to the callback one at a time instead of loading the whole result.
+// Iteration stops when the context is cancelled or the callback returns an error; ErrStop ends it silently,
+// any other error is thrown. Returns the number of processed rows.
+func Each[T any](ctx context.Context, handler rowsHandler, builder Builder, callback func(T) error) int {
+	query, args := builder.scoped(ctx).Returning("*").Build()
+
+	count, err := observe(ctx, query, *args, func(ctx context.Context) (int, error) {
+		rows, err := handler(ctx, query, *args...)
+		if err != nil {
+			return 0, err
+		}
+		return ScanEach(ctx, rows, callback)
+	})
+	if !errors.Is(err, ErrStop) {
+		try.ThrowError(err)
+	}
+	return count
+}
+
+// EachCursor runs the query through a server-side cursor with the given name, fetching batch rows at a time with
+// handler, and passes the rows to the callback like Each. The cursor must live in a transaction, so both handlers
+// should belong to one. The whole iteration is reported to hooks as a single execution of the query.
+func EachCursor[T any](ctx context.Context, exec execHandler, handler rowsHandler, name string, builder Builder, batch int, callback func(T) error) int {
+	query, args := builder.scoped(ctx).Build()
+
+	count, err := observe(ctx, query, *args, func(ctx context.Context) (int, error) {
+		if _, err := exec(ctx, fmt.Sprintf("declare %s no scroll cursor for %s", name, query), *args...); err != nil {
+			return 0, err
+		}
+		total := 0
+		for {
+			rows, err := handler(ctx, fmt.Sprintf("fetch forward %d from %s", batch, name))
+			if err != nil {
+				return total, err
+			}
+			count, err := ScanEach(ctx, rows, callback)
+			total += count
+			if err != nil {
+				return total, err
+			}
+			if count < batch {
+				break
+			}
+		}
+		_, err := exec(ctx, fmt.Sprintf("close %s", name))
+		return total, err
+	})
+	if !errors.Is(err, ErrStop) {
+		try.ThrowError(err)
+	}
+	return count
+}
+
+// ScanEach scans the rows into T one by one and closes them. Structs are scanned by column name, other types
+// (including sql.Scanner implementations) from a single column.
+func ScanEach[T any](ctx context.Context, rows *sqlx.Rows, callback func(T) error) (int, error) {
+	defer func() { _ = rows.Close() }()
+
+	count := 0
+	structured := isStructScan(reflect.TypeOf(new(T)).Elem())
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		var item T
+		var err error
+		if structured {
+			err = rows.StructScan(&item)
+		} else {
+			err = rows.Scan(&item)
+		}
+		if err != nil {
+			return count, err
+		}
+		if err = callback(item); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func isStructScan(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*dbsql.Scanner)(nil)).Elem()) {
+		return false
+	}
+	return t.Kind() == reflect.Struct && !t.ConvertibleTo(reflect.TypeOf(time.Time{}))
+}
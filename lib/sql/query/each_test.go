@@ -0,0 +1,102 @@
+package query
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type rowsDriver struct{}
+
+func (rowsDriver) Open(string) (driver.Conn, error) { return rowsConn{}, nil }
+
+type rowsConn struct{}
+
+func (rowsConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (rowsConn) Close() error                        { return nil }
+func (rowsConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf("not supported") }
+
+func (rowsConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{count: args[0].Value.(int64)}, nil
+}
+
+type fakeRows struct {
+	count, current int64
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.current >= r.count {
+		return io.EOF
+	}
+	r.current++
+	dest[0], dest[1] = r.current, fmt.Sprintf("name %d", r.current)
+	return nil
+}
+
+func init() {
+	dbsql.Register("glb-rows", rowsDriver{})
+}
+
+func TestEach(t *testing.T) {
+	db := sqlx.MustOpen("glb-rows", "")
+	defer func() { _ = db.Close() }()
+
+	type row struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	t.Run(
+		"Struct",
+		func(t *testing.T) {
+			var rows []row
+			count := Each(context.Background(), db.QueryxContext, NewBuilder("test").Select("id", "name").Where(And("count", "le", 3)).NotSort(), func(item row) error {
+				rows = append(rows, item)
+				return nil
+			})
+			assert.Equal(t, 3, count)
+			assert.Equal(t, []row{{1, "name 1"}, {2, "name 2"}, {3, "name 3"}}, rows)
+		},
+	)
+
+	t.Run(
+		"Stop",
+		func(t *testing.T) {
+			count := Each(context.Background(), db.QueryxContext, NewBuilder("test").Select("id", "name").Where(And("count", "le", 5)).NotSort(), func(item row) error {
+				if item.Id == 3 {
+					return ErrStop
+				}
+				return nil
+			})
+			assert.Equal(t, 2, count)
+
+			assert.PanicsWithError(t, "failed", func() {
+				Each(context.Background(), db.QueryxContext, NewBuilder("test").Select("id", "name").Where(And("count", "le", 5)).NotSort(), func(item row) error {
+					return fmt.Errorf("failed")
+				})
+			})
+		},
+	)
+
+	t.Run(
+		"Cancel",
+		func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			assert.PanicsWithError(t, "context canceled", func() {
+				Each(ctx, db.QueryxContext, NewBuilder("test").Select("id", "name").Where(And("count", "le", 5)).NotSort(), func(item row) error {
+					cancel()
+					return nil
+				})
+			})
+		},
+	)
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/betam/glb/lib/pointer"
-	"github.com/betam/glb/lib/try"
 	"reflect"
 	"strings"
 )
@@ -21,48 +20,89 @@ type Expression interface {
 }
 
 func And(expressions ...any) Expression {
-	return pointer.Pointer(expression{strategyAnd, []any{}}).Add(expressions...)
+	return pointer.Pointer(expression{strategy: strategyAnd, parts: []any{}}).Add(expressions...)
 }
 
 func Or(expressions ...any) Expression {
-	return pointer.Pointer(expression{strategyOr, []any{}}).Add(expressions...)
+	return pointer.Pointer(expression{strategy: strategyOr, parts: []any{}}).Add(expressions...)
+}
+
+func Not(expressions ...any) Expression {
+	if len(expressions) == 1 {
+		if e, ok := expressions[0].(*expression); ok {
+			negated := *e
+			negated.parts = append([]any{}, e.parts...)
+			negated.negate = !e.negate
+			return &negated
+		}
+	}
+	e := And(expressions...).(*expression)
+	e.negate = true
+	return e
+}
+
+func Exists(subquery Builder) Expression {
+	return And("", "exists", subquery)
+}
+
+func NotExists(subquery Builder) Expression {
+	return And("", "not_exists", subquery)
 }
 
 type expression struct {
 	strategy string
 	parts    []any
+	negate   bool
 }
 
 func (e *expression) UnmarshalJSON(bytes []byte) error {
 	e.parts = []any{}
 	var parser func(*expression, any) error
 	parser = func(e *expression, data any) error {
-		if part, ok := data.(map[string]any); ok {
-			if mode, ok := part["mode"].(string); ok {
-				e.strategy = mode
-			}
-			if part["conditions"] == nil {
-				return fmt.Errorf("condition is required but missing")
-			}
-			for _, condition := range part["conditions"].([]any) {
-				if _, ok := condition.(map[string]any); ok {
-					nested := &expression{}
-					err := parser(nested, condition)
-					if err != nil {
-						return err
-					}
-					e.Add(nested)
-				} else {
-					e.Add(condition.([]any)...)
+		part, ok := data.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expression must be an object")
+		}
+		if mode, ok := part["mode"].(string); ok {
+			e.strategy = mode
+		}
+		if negate, ok := part["not"].(bool); ok {
+			e.negate = negate
+		}
+		if part["conditions"] == nil {
+			return fmt.Errorf("condition is required but missing")
+		}
+		conditions, ok := part["conditions"].([]any)
+		if !ok {
+			return fmt.Errorf("conditions must be an array")
+		}
+		for _, condition := range conditions {
+			if _, ok := condition.(map[string]any); ok {
+				nested := &expression{}
+				err := parser(nested, condition)
+				if err != nil {
+					return err
+				}
+				e.Add(nested)
+			} else if operation, ok := condition.([]any); ok && len(operation) == 3 {
+				if _, ok := operation[0].(string); !ok {
+					return fmt.Errorf("condition field must be a string")
 				}
+				if _, ok := operation[1].(string); !ok {
+					return fmt.Errorf("condition operation must be a string")
+				}
+				e.parts = append(e.parts, operation)
+			} else {
+				return fmt.Errorf("condition must be an object or [field, operation, value]")
 			}
 		}
 		return nil
 	}
 	data := map[string]any{}
-	try.ThrowError(json.Unmarshal(bytes, &data))
-	try.ThrowError(parser(e, data))
-	return nil
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return err
+	}
+	return parser(e, data)
 }
 
 func (e *expression) MarshalJSON() ([]byte, error) {
@@ -70,6 +110,9 @@ func (e *expression) MarshalJSON() ([]byte, error) {
 		"mode":       e.strategy,
 		"conditions": e.parts,
 	}
+	if e.negate {
+		data["not"] = true
+	}
 	return json.Marshal(data)
 }
 
@@ -105,16 +148,17 @@ func (e *expression) query(params *[]any) (string, *[]any) {
 	var query []string
 	var q string
 	for _, part := range e.parts {
+		if nested, ok := part.(*expression); ok && nested.predicate() {
+			part = nested.parts[0]
+		}
 		if expr, ok := part.(Expression); ok {
 			q, params = expr.query(params)
-			query = append(query, q)
+			if q != "" {
+				query = append(query, q)
+			}
 		} else {
-			v := reflect.ValueOf(part)
-			field := v.Index(0).Elem().String()
-			operation := v.Index(1).Elem().String()
-			value := v.Index(2).Interface()
-
-			q, params = e.build(field, value, operation, params)
+			c := condition(part)
+			q, params = e.build(c.Field, c.Value, c.Operation, params)
 			query = append(query, q)
 		}
 	}
@@ -125,46 +169,122 @@ func (e *expression) query(params *[]any) (string, *[]any) {
 	if e.strategy != strategyAnd && e.strategy != strategyOr {
 		panic(fmt.Errorf("unsupported query mode: '%s'", e.strategy))
 	}
-	return fmt.Sprintf("(%s)", strings.Join(query, fmt.Sprintf(") %s (", e.strategy))), params
+	if len(query) == 0 {
+		return "", params
+	}
+	q = fmt.Sprintf("(%s)", strings.Join(query, fmt.Sprintf(") %s (", e.strategy)))
+	if e.negate {
+		q = fmt.Sprintf("not (%s)", q)
+	}
+	return q, params
+}
+
+// predicate reports whether e is a single exists condition, which does not need its own parentheses.
+func (e *expression) predicate() bool {
+	if e.negate || len(e.parts) != 1 {
+		return false
+	}
+	if _, ok := e.parts[0].(Expression); ok {
+		return false
+	}
+	operation := reflect.ValueOf(e.parts[0]).Index(1).Elem().String()
+	return operation == "exists" || operation == "not_exists"
 }
 
 func (e *expression) build(field string, value any, operation string, params *[]any) (string, *[]any) {
 	operationsList := map[string]string{
-		"eq":      "=",
-		"lt":      "<",
-		"le":      "<=",
-		"gt":      ">",
-		"ge":      ">=",
-		"ne":      "!=",
-		"json_eq": "?",
-		"json_in": "@>",
+		"eq":               "=",
+		"lt":               "<",
+		"le":               "<=",
+		"gt":               ">",
+		"ge":               ">=",
+		"ne":               "!=",
+		"json_eq":          "?",
+		"json_in":          "@>",
+		"json_contained":   "<@",
+		"json_path_exists": "@?",
+		"json_path_match":  "@@",
+		"like":             "like",
+		"ilike":            "ilike",
+		"not_like":         "not like",
+		"not_ilike":        "not ilike",
+		"distinct":         "is distinct from",
+		"not_distinct":     "is not distinct from",
+	}
+	searchList := map[string]string{
+		"fts":       "to_tsquery",
+		"fts_plain": "plainto_tsquery",
+		"fts_web":   "websearch_to_tsquery",
+	}
+
+	if r, ok := value.(*raw); ok {
+		if op, ok := operationsList[operation]; ok {
+			return fmt.Sprintf("%s %s %s", field, op, r.expression), params
+		}
+	}
+
+	switch operation {
+	case "in", "not_in":
+		op := map[string]string{"in": "in", "not_in": "not in"}[operation]
+		if sub, ok := value.(Builder); ok {
+			subquery, _ := sub.parameters(params).Build()
+			return fmt.Sprintf("%s %s (%s)", field, op, subquery), params
+		}
+		if !isList(value) {
+			panic(fmt.Errorf("operation '%s' requires list or subquery value", operation))
+		}
+		return e.list(field, op, value, params), params
+	case "between", "not_between":
+		v := reflect.ValueOf(value)
+		if !isList(value) || v.Len() != 2 {
+			panic(fmt.Errorf("operation '%s' requires list of 2 values", operation))
+		}
+		op := map[string]string{"between": "between", "not_between": "not between"}[operation]
+		*params = append(*params, v.Index(0).Interface(), v.Index(1).Interface())
+		return fmt.Sprintf("%s %s $%d and $%d", field, op, len(*params)-1, len(*params)), params
+	case "any", "all":
+		if value == nil {
+			panic(fmt.Errorf("operation '%s' does not support null value", operation))
+		}
+		*params = append(*params, value)
+		return fmt.Sprintf("$%d = %s(%s)", len(*params), operation, field), params
+	case "exists", "not_exists":
+		sub, ok := value.(Builder)
+		if !ok {
+			panic(fmt.Errorf("operation '%s' requires subquery value", operation))
+		}
+		op := map[string]string{"exists": "exists", "not_exists": "not exists"}[operation]
+		subquery, _ := sub.parameters(params).Build()
+		return fmt.Sprintf("%s (%s)", op, subquery), params
+	}
+
+	if function, ok := searchList[operation]; ok {
+		if _, ok := value.(string); !ok {
+			panic(fmt.Errorf("operation '%s' requires string value", operation))
+		}
+		*params = append(*params, value)
+		return fmt.Sprintf("%s @@ %s($%d)", field, function, len(*params)), params
 	}
 
 	if op, ok := operationsList[operation]; ok {
 		if value == nil || reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil() {
-			op = map[string]string{
-				"=":  "is null",
-				"!=": "is not null",
-			}[op]
-			return fmt.Sprintf("%s %s", field, op), params
-		} else if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
-			op = map[string]string{
+			if nullable, ok := map[string]string{"=": "is null", "!=": "is not null"}[op]; ok {
+				return fmt.Sprintf("%s %s", field, nullable), params
+			}
+			if operation != "distinct" && operation != "not_distinct" {
+				panic(fmt.Errorf("operation '%s' does not support null value", operation))
+			}
+			return fmt.Sprintf("%s %s null", field, op), params
+		} else if isList(value) {
+			listOp, ok := map[string]string{
 				"=":  "in",
 				"!=": "not in",
 				"?":  "?|",
 			}[op]
-			list := make([]string, 0, v.Len())
-			for i := 0; i < v.Len(); i++ {
-				*params = append(*params, v.Index(i).Interface())
-				list = append(list, fmt.Sprintf("$%d", len(*params)))
+			if !ok {
+				panic(fmt.Errorf("operation '%s' does not support list value", operation))
 			}
-			var in string
-			if op == "?|" {
-				in = fmt.Sprintf("array[%s]", strings.Join(list, ","))
-			} else {
-				in = fmt.Sprintf("(%s)", strings.Join(list, ","))
-			}
-			return fmt.Sprintf("%s %s %s", field, op, in), params
+			return e.list(field, listOp, value, params), params
 		} else {
 			*params = append(*params, value)
 			return fmt.Sprintf("%s %s $%d", field, op, len(*params)), params
@@ -172,3 +292,30 @@ func (e *expression) build(field string, value any, operation string, params *[]
 	}
 	panic(fmt.Errorf("unsupported sql operation: '%s'", operation))
 }
+
+func (e *expression) list(field string, op string, value any, params *[]any) string {
+	v := reflect.ValueOf(value)
+	if v.Len() == 0 {
+		return map[string]string{"in": "false", "not in": "true", "?|": "false"}[op]
+	}
+	list := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		*params = append(*params, v.Index(i).Interface())
+		list = append(list, fmt.Sprintf("$%d", len(*params)))
+	}
+	if op == "?|" {
+		return fmt.Sprintf("%s %s array[%s]", field, op, strings.Join(list, ","))
+	}
+	return fmt.Sprintf("%s %s (%s)", field, op, strings.Join(list, ","))
+}
+
+func isList(value any) bool {
+	if value == nil {
+		return false
+	}
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
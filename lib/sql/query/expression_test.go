@@ -69,4 +69,90 @@ func TestExpression(t *testing.T) {
 			assert.PanicsWithError(t, "unsupported sql operation: 'some'", func() { And("field", "some", 17).Build() })
 		},
 	)
+
+	t.Run(
+		"Column",
+		func(t *testing.T) {
+			sql, params := And("a.id", "eq", Column("b.a_id")).Add("a.value", "gt", Column("b.value")).Add("b.kind", "eq", 1).Build()
+			assert.Equal(t, "(a.id = b.a_id) and (a.value > b.value) and (b.kind = $1)", sql)
+			assert.Equal(t, &[]any{1}, params)
+		},
+	)
+
+	t.Run(
+		"Operations",
+		func(t *testing.T) {
+			sub := NewBuilder("orders").Select("user_id").Where(And("total", "gt", 100)).NotSort()
+			sql, params := And("name", "ilike", "jo%").
+				Add("name", "not_like", "%bot").
+				Add("age", "between", []int{18, 65}).
+				Add("status", "not_in", []string{"banned", "deleted"}).
+				Add("tags", "any", "vip").
+				Add("scores", "all", 10).
+				Add("id", "in", sub).
+				Add(Exists(NewBuilder("sessions").Where(And("sessions.user_id", "eq", Column("users.id"))).NotSort())).
+				Add("document", "fts_web", "fat cat").
+				Add("meta", "json_path_exists", "$.tags[*]").
+				Add("meta", "json_contained", `{"a":1}`).
+				Add("manager", "distinct", nil).
+				Add("deputy", "not_distinct", 7).
+				Add("kind", "in", []int{}).
+				Build()
+			assert.Equal(
+				t,
+				"(name ilike $1) and (name not like $2) and (age between $3 and $4) and (status not in ($5,$6)) and ($7 = any(tags)) and ($8 = all(scores)) and (id in (select user_id from orders where (total > $9))) and (exists (select * from sessions where (sessions.user_id = users.id))) and (document @@ websearch_to_tsquery($10)) and (meta @? $11) and (meta <@ $12) and (manager is distinct from null) and (deputy is not distinct from $13) and (false)",
+				sql,
+			)
+			assert.Equal(t, &[]any{"jo%", "%bot", 18, 65, "banned", "deleted", "vip", 10, 100, "fat cat", "$.tags[*]", `{"a":1}`, 7}, params)
+
+			sql, _ = Or("deleted", "eq", true).Add(NotExists(NewBuilder("orders").NotSort())).Build()
+			assert.Equal(t, "(deleted = $1) or (not exists (select * from orders))", sql)
+
+			q := And()
+			try.ThrowError(json.Unmarshal([]byte(`{"mode":"and","conditions":[["name","like","a%"],["age","between",[1,2]],["id","not_in",[3]],["doc","fts","cat"]]}`), &q))
+			sql, params = q.Build()
+			assert.Equal(t, "(name like $1) and (age between $2 and $3) and (id not in ($4)) and (doc @@ to_tsquery($5))", sql)
+			assert.Equal(t, &[]any{"a%", 1., 2., 3., "cat"}, params)
+
+			assert.PanicsWithError(t, "operation 'between' requires list of 2 values", func() { And("a", "between", []int{1}).Build() })
+			assert.PanicsWithError(t, "operation 'exists' requires subquery value", func() { And("", "exists", 1).Build() })
+			assert.PanicsWithError(t, "operation 'lt' does not support null value", func() { And("a", "lt", nil).Build() })
+			assert.PanicsWithError(t, "operation 'like' does not support list value", func() { And("a", "like", []string{"b"}).Build() })
+		},
+	)
+
+	t.Run(
+		"Not",
+		func(t *testing.T) {
+			inner := Or("a", "eq", 1).Add("b", "eq", 2)
+			sql, params := And("c", "gt", 0).Add(Not(inner)).Add(Not("d", "like", "x%")).Build()
+			assert.Equal(t, "(c > $1) and (not ((a = $2) or (b = $3))) and (not ((d like $4)))", sql)
+			assert.Equal(t, &[]any{0, 1, 2, "x%"}, params)
+
+			sql, _ = inner.Build()
+			assert.Equal(t, "(a = $1) or (b = $2)", sql)
+
+			sql, _ = And("c", "gt", 0).Add(Not(Exists(NewBuilder("orders").NotSort()))).Build()
+			assert.Equal(t, "(c > $1) and (not ((exists (select * from orders))))", sql)
+
+			qJson := try.Throw(json.Marshal(Not(inner)))
+			assert.Equal(t, `{"conditions":[["a","eq",1],["b","eq",2]],"mode":"or","not":true}`, string(qJson))
+
+			q := And()
+			try.ThrowError(json.Unmarshal(qJson, &q))
+			sql, _ = q.Build()
+			assert.Equal(t, "not ((a = $1) or (b = $2))", sql)
+		},
+	)
+
+	t.Run(
+		"MalformedJson",
+		func(t *testing.T) {
+			q := And()
+			assert.EqualError(t, json.Unmarshal([]byte(`{"conditions":{"a":1}}`), &q), "conditions must be an array")
+			assert.EqualError(t, json.Unmarshal([]byte(`{"conditions":["a"]}`), &q), "condition must be an object or [field, operation, value]")
+			assert.EqualError(t, json.Unmarshal([]byte(`{"conditions":[[1,"eq",1]]}`), &q), "condition field must be a string")
+			assert.EqualError(t, json.Unmarshal([]byte(`{"conditions":[{"mode":"or"}]}`), &q), "condition is required but missing")
+		},
+	)
 }
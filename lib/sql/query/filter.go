@@ -0,0 +1,366 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/betam/glb/lib/list"
+	"github.com/betam/glb/lib/try"
+)
+
+var ErrInvalidFilter = fmt.Errorf("invalid filter")
+
+const (
+	FilterAny uint8 = iota
+	FilterString
+	FilterNumber
+	FilterInteger
+	FilterBool
+	FilterTime
+	FilterArray
+	FilterJson
+)
+
+var (
+	comparisonOperations = []string{"eq", "ne", "lt", "le", "gt", "ge", "in", "not_in", "between", "not_between", "distinct", "not_distinct"}
+	// filterOperations lists the operations supported by every field type; Operations of a field are limited to them.
+	filterOperations = map[uint8][]string{
+		FilterAny:     comparisonOperations,
+		FilterString:  append([]string{"like", "ilike", "not_like", "not_ilike", "fts", "fts_plain", "fts_web"}, comparisonOperations...),
+		FilterNumber:  comparisonOperations,
+		FilterInteger: comparisonOperations,
+		FilterBool:    {"eq", "ne", "in", "not_in", "distinct", "not_distinct"},
+		FilterTime:    comparisonOperations,
+		FilterArray:   {"any", "all"},
+		FilterJson:    {"json_eq", "json_in", "json_contained", "json_path_exists", "json_path_match"},
+	}
+)
+
+// FilterField describes a filterable field. Operations default to every operation supported by the Type; values of
+// FilterArray fields are compared with any and all as scalars.
+type FilterField struct {
+	Column     string
+	Type       uint8
+	Operations []string
+	Nullable   bool
+}
+
+type FilterSchema struct {
+	Fields      map[string]FilterField
+	Sort        []string
+	DefaultSort []string
+	MaxCount    int
+}
+
+type FilterError struct {
+	Path    string
+	Message string
+}
+
+func (e FilterError) Error() string {
+	return fmt.Sprintf("%v: %s: %s", ErrInvalidFilter, e.Path, e.Message)
+}
+
+func (e FilterError) Unwrap() error {
+	return ErrInvalidFilter
+}
+
+type filterPayload struct {
+	Filter json.RawMessage `json:"filter"`
+	Sort   []string        `json:"sort"`
+	Page   *struct {
+		Page  int `json:"page"`
+		Count int `json:"count"`
+	} `json:"page"`
+	After  *Cursor `json:"after"`
+	Before *Cursor `json:"before"`
+}
+
+// ParseFilter applies a client filter payload to the builder:
+//
+//	{
+//	  "filter": {"mode": "and", "not": false, "conditions": [["field", "operation", value], {nested filter}]},
+//	  "sort":   ["field desc", "other"],
+//	  "page":   {"page": 0, "count": 20},
+//	  "after":  "cursor", "before": "cursor"
+//	}
+//
+// Every key is optional. Fields, operations, value types and sort columns are limited by the FilterSchema, and the
+// operations of a field by its type; cursors must hold a value per sort column. A missing or zero page count falls
+// back to MaxCount.
+func ParseFilter(body []byte, builder Builder, schema FilterSchema) Builder {
+	payload := filterPayload{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			panic(FilterError{"$", err.Error()})
+		}
+	}
+
+	if len(payload.Filter) > 0 && string(payload.Filter) != "null" {
+		var data any
+		decoder := json.NewDecoder(bytes.NewReader(payload.Filter))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			panic(FilterError{"filter", err.Error()})
+		}
+		builder.Where(parseFilterGroup(data, "filter", schema))
+	}
+
+	sort := schema.DefaultSort
+	if len(payload.Sort) > 0 {
+		sort = list.Map(payload.Sort, func(item string) string { return parseFilterSort(item, schema) })
+	}
+	if len(sort) > 0 {
+		builder.Sort(sort...)
+	}
+
+	if payload.Page != nil {
+		if payload.Page.Page < 0 || payload.Page.Count < 0 {
+			panic(FilterError{"page", "page and count must not be negative"})
+		}
+		count := payload.Page.Count
+		if schema.MaxCount > 0 && count > schema.MaxCount {
+			panic(FilterError{"page.count", fmt.Sprintf("must not exceed %d", schema.MaxCount)})
+		}
+		if count == 0 {
+			count = schema.MaxCount
+		}
+		builder.Page(payload.Page.Page, count)
+	} else if schema.MaxCount > 0 {
+		builder.Page(0, schema.MaxCount)
+	}
+
+	if payload.After != nil && payload.Before != nil {
+		panic(FilterError{"after", "cannot be used together with before"})
+	}
+	for key, cursor := range map[string]*Cursor{"after": payload.After, "before": payload.Before} {
+		if cursor == nil {
+			continue
+		}
+		columns := sortColumns(builder, sort)
+		if columns == 0 {
+			panic(FilterError{key, "requires a sort"})
+		}
+		if len(cursor.values) != columns {
+			panic(FilterError{key, fmt.Sprintf("must contain %d values, one per sort column", columns)})
+		}
+	}
+	if payload.After != nil {
+		builder.After(payload.After)
+	}
+	if payload.Before != nil {
+		builder.Before(payload.Before)
+	}
+	return builder
+}
+
+// sortColumns returns the number of columns the builder sorts by, including its default sort.
+func sortColumns(target Builder, sort []string) int {
+	if b, ok := target.(*builder); ok {
+		return len(b.sorting(b.alias))
+	}
+	return len(sort)
+}
+
+func parseFilterGroup(data any, path string, schema FilterSchema) Expression {
+	group, ok := data.(map[string]any)
+	if !ok {
+		panic(FilterError{path, "must be an object"})
+	}
+	for key := range group {
+		if !list.Contains(key, []string{"mode", "not", "conditions"}) {
+			panic(FilterError{path, fmt.Sprintf("unknown key '%s'", key)})
+		}
+	}
+
+	e := &expression{strategy: strategyAnd, parts: []any{}}
+	if mode, ok := group["mode"]; ok {
+		if mode != strategyAnd && mode != strategyOr {
+			panic(FilterError{path + ".mode", "must be 'and' or 'or'"})
+		}
+		e.strategy = mode.(string)
+	}
+	if negate, ok := group["not"]; ok {
+		if e.negate, ok = negate.(bool); !ok {
+			panic(FilterError{path + ".not", "must be a boolean"})
+		}
+	}
+	conditions, ok := group["conditions"].([]any)
+	if !ok {
+		panic(FilterError{path + ".conditions", "must be an array"})
+	}
+	for idx, condition := range conditions {
+		conditionPath := fmt.Sprintf("%s.conditions[%d]", path, idx)
+		if _, ok := condition.(map[string]any); ok {
+			e.parts = append(e.parts, parseFilterGroup(condition, conditionPath, schema))
+		} else {
+			e.parts = append(e.parts, parseFilterCondition(condition, conditionPath, schema))
+		}
+	}
+	return e
+}
+
+func parseFilterCondition(data any, path string, schema FilterSchema) []any {
+	condition, ok := data.([]any)
+	if !ok || len(condition) != 3 {
+		panic(FilterError{path, "must be an object or [field, operation, value]"})
+	}
+	name, ok := condition[0].(string)
+	if !ok {
+		panic(FilterError{path + "[0]", "field must be a string"})
+	}
+	field, ok := schema.Fields[name]
+	if !ok {
+		panic(FilterError{path + "[0]", fmt.Sprintf("field '%s' is not allowed", name)})
+	}
+	operation, ok := condition[1].(string)
+	if !ok {
+		panic(FilterError{path + "[1]", "operation must be a string"})
+	}
+	supported, ok := filterOperations[field.Type]
+	if !ok {
+		panic(fmt.Errorf("unsupported filter type %d of field '%s'", field.Type, name))
+	}
+	allowed := field.Operations
+	if len(allowed) == 0 {
+		allowed = supported
+	}
+	if !list.Contains(operation, allowed) || !list.Contains(operation, supported) {
+		panic(FilterError{path + "[1]", fmt.Sprintf("operation '%s' is not allowed for field '%s'", operation, name)})
+	}
+
+	column := field.Column
+	if column == "" {
+		column = name
+	}
+	valuePath := path + "[2]"
+	value := condition[2]
+	switch operation {
+	case "in", "not_in", "between", "not_between":
+		values, ok := value.([]any)
+		if !ok {
+			panic(FilterError{valuePath, "must be an array"})
+		}
+		if (operation == "between" || operation == "not_between") && len(values) != 2 {
+			panic(FilterError{valuePath, "must contain exactly 2 values"})
+		}
+		result := make([]any, 0, len(values))
+		for idx, item := range values {
+			result = append(result, convertFilterValue(item, fmt.Sprintf("%s[%d]", valuePath, idx), field.Type, false))
+		}
+		return []any{column, operation, result}
+	case "json_eq":
+		if values, ok := value.([]any); ok {
+			result := make([]any, 0, len(values))
+			for idx, item := range values {
+				result = append(result, convertFilterValue(item, fmt.Sprintf("%s[%d]", valuePath, idx), FilterString, false))
+			}
+			return []any{column, operation, result}
+		}
+		return []any{column, operation, convertFilterValue(value, valuePath, FilterString, false)}
+	case "json_path_exists", "json_path_match":
+		return []any{column, operation, convertFilterValue(value, valuePath, FilterString, false)}
+	case "any", "all":
+		return []any{column, operation, convertFilterValue(value, valuePath, FilterAny, false)}
+	case "eq", "ne":
+		if values, ok := value.([]any); ok {
+			result := make([]any, 0, len(values))
+			for idx, item := range values {
+				result = append(result, convertFilterValue(item, fmt.Sprintf("%s[%d]", valuePath, idx), field.Type, false))
+			}
+			return []any{column, operation, result}
+		}
+		return []any{column, operation, convertFilterValue(value, valuePath, field.Type, field.Nullable)}
+	case "distinct", "not_distinct":
+		return []any{column, operation, convertFilterValue(value, valuePath, field.Type, field.Nullable)}
+	case "json_in", "json_contained":
+		if value == nil {
+			panic(FilterError{valuePath, "must not be null"})
+		}
+		return []any{column, operation, string(try.Throw(json.Marshal(value)))}
+	}
+	return []any{column, operation, convertFilterValue(value, valuePath, field.Type, false)}
+}
+
+func convertFilterValue(value any, path string, kind uint8, nullable bool) any {
+	if value == nil {
+		if !nullable {
+			panic(FilterError{path, "must not be null"})
+		}
+		return nil
+	}
+	switch kind {
+	case FilterString:
+		if v, ok := value.(string); ok {
+			return v
+		}
+		panic(FilterError{path, "must be a string"})
+	case FilterNumber:
+		if v, ok := value.(json.Number); ok {
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+		panic(FilterError{path, "must be a number"})
+	case FilterInteger:
+		if v, ok := value.(json.Number); ok {
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		}
+		panic(FilterError{path, "must be an integer"})
+	case FilterBool:
+		if v, ok := value.(bool); ok {
+			return v
+		}
+		panic(FilterError{path, "must be a boolean"})
+	case FilterTime:
+		if v, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t
+			}
+		}
+		panic(FilterError{path, "must be a RFC 3339 time"})
+	default:
+		switch v := value.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+			panic(FilterError{path, "must be a number"})
+		case string, bool:
+			return v
+		}
+		panic(FilterError{path, "must be a scalar value"})
+	}
+}
+
+func parseFilterSort(sort string, schema FilterSchema) string {
+	parts := strings.Fields(sort)
+	if len(parts) == 0 || len(parts) > 2 {
+		panic(FilterError{"sort", fmt.Sprintf("unsupported sort '%s'", sort)})
+	}
+	if !list.Contains(parts[0], schema.Sort) {
+		panic(FilterError{"sort", fmt.Sprintf("sort by '%s' is not allowed", parts[0])})
+	}
+	direction := "asc"
+	if len(parts) == 2 {
+		direction = strings.ToLower(parts[1])
+		if direction != "asc" && direction != "desc" {
+			panic(FilterError{"sort", fmt.Sprintf("unsupported sort direction '%s'", parts[1])})
+		}
+	}
+	column := parts[0]
+	if field, ok := schema.Fields[column]; ok && field.Column != "" {
+		column = field.Column
+	}
+	return fmt.Sprintf("%s %s", column, direction)
+}
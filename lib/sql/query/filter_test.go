@@ -0,0 +1,106 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	schema := FilterSchema{
+		Fields: map[string]FilterField{
+			"name":    {Type: FilterString, Operations: []string{"eq", "like", "in"}},
+			"age":     {Column: "u.age", Type: FilterInteger},
+			"score":   {Type: FilterNumber},
+			"created": {Type: FilterTime, Operations: []string{"ge", "lt"}},
+			"manager": {Type: FilterInteger, Nullable: true},
+			"tags":    {Type: FilterArray},
+			"meta":    {Type: FilterJson},
+			"active":  {Type: FilterBool},
+		},
+		Sort:        []string{"name", "age"},
+		DefaultSort: []string{"id desc"},
+		MaxCount:    50,
+	}
+
+	t.Run(
+		"Success",
+		func(t *testing.T) {
+			body := []byte(`{
+				"filter": {"mode": "or", "conditions": [
+					["name", "like", "jo%"],
+					{"not": true, "conditions": [["age", "between", [18, 65]], ["manager", "eq", null]]},
+					["created", "ge", "2024-01-02T03:04:05Z"],
+					["score", "gt", 2.5]
+				]},
+				"sort": ["age desc", "name"],
+				"page": {"page": 2, "count": 10}
+			}`)
+			q, params := ParseFilter(body, NewBuilder("users"), schema).Build()
+			assert.Equal(t, "select * from users where (name like $1) or (not ((u.age between $2 and $3) and (manager is null))) or (created >= $4) or (score > $5) order by u.age desc,name asc offset 20 limit 10", q)
+			assert.Equal(t, &[]any{"jo%", int64(18), int64(65), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 2.5}, params)
+		},
+	)
+
+	t.Run(
+		"Defaults",
+		func(t *testing.T) {
+			q, params := ParseFilter(nil, NewBuilder("users"), schema).Build()
+			assert.Equal(t, "select * from users order by id desc limit 50", q)
+			assert.Empty(t, *params)
+
+			q, params = ParseFilter([]byte(`{"filter":{"conditions":[]},"after":"WzVd"}`), NewBuilder("users"), schema).Build()
+			assert.Equal(t, "select * from users where (id < $1) order by id desc limit 50", q)
+			assert.Len(t, *params, 1)
+
+			q, _ = ParseFilter([]byte(`{"page":{"page":2,"count":0}}`), NewBuilder("users"), schema).Build()
+			assert.Equal(t, "select * from users order by id desc offset 100 limit 50", q)
+
+			q, params = ParseFilter([]byte(`{"filter":{"conditions":[["tags","any","a"],["meta","json_eq","key"],["name","eq","b"]]},"sort":["name"],"after":"WyJhIl0"}`), NewBuilder("users"), schema).Build()
+			assert.Equal(t, "select * from users where ($1 = any(tags)) and (meta ? $2) and (name = $3) and (name > $4) order by name asc limit 50", q)
+			assert.Equal(t, &[]any{"a", "key", "b", "a"}, params)
+		},
+	)
+
+	t.Run(
+		"Errors",
+		func(t *testing.T) {
+			cases := map[string]string{
+				`{"filter":{"conditions":[["password","eq","x"]]}}`:        "invalid filter: filter.conditions[0][0]: field 'password' is not allowed",
+				`{"filter":{"conditions":[["name","gt","x"]]}}`:            "invalid filter: filter.conditions[0][1]: operation 'gt' is not allowed for field 'name'",
+				`{"filter":{"conditions":[["age","eq","x"]]}}`:             "invalid filter: filter.conditions[0][2]: must be an integer",
+				`{"filter":{"conditions":[["age","eq",null]]}}`:            "invalid filter: filter.conditions[0][2]: must not be null",
+				`{"filter":{"conditions":[["age","between",[1]]]}}`:        "invalid filter: filter.conditions[0][2]: must contain exactly 2 values",
+				`{"filter":{"conditions":[["age","eq"]]}}`:                 "invalid filter: filter.conditions[0]: must be an object or [field, operation, value]",
+				`{"filter":{"conditions":{"a":1}}}`:                        "invalid filter: filter.conditions: must be an array",
+				`{"filter":{"mode":"xor","conditions":[]}}`:                "invalid filter: filter.mode: must be 'and' or 'or'",
+				`{"filter":{"conditions":[{"conditions":[[1,"eq",1]]}]}}`:  "invalid filter: filter.conditions[0].conditions[0][0]: field must be a string",
+				`{"filter":{"conditions":[["created","ge","yesterday"]]}}`: "invalid filter: filter.conditions[0][2]: must be a RFC 3339 time",
+				`{"filter":{"conditions":[["age","exists",1]]}}`:           "invalid filter: filter.conditions[0][1]: operation 'exists' is not allowed for field 'age'",
+				`{"filter":{"conditions":[["age","like","1%"]]}}`:          "invalid filter: filter.conditions[0][1]: operation 'like' is not allowed for field 'age'",
+				`{"filter":{"conditions":[["age","fts","x"]]}}`:            "invalid filter: filter.conditions[0][1]: operation 'fts' is not allowed for field 'age'",
+				`{"filter":{"conditions":[["age","any",1]]}}`:              "invalid filter: filter.conditions[0][1]: operation 'any' is not allowed for field 'age'",
+				`{"filter":{"conditions":[["active","gt",true]]}}`:         "invalid filter: filter.conditions[0][1]: operation 'gt' is not allowed for field 'active'",
+				`{"filter":{"conditions":[["tags","eq","a"]]}}`:            "invalid filter: filter.conditions[0][1]: operation 'eq' is not allowed for field 'tags'",
+				`{"filter":{"conditions":[["meta","json_eq",1]]}}`:         "invalid filter: filter.conditions[0][2]: must be a string",
+				`{"after":"WzVd","sort":["name","age"]}`:                   "invalid filter: after: must contain 2 values, one per sort column",
+				`{"before":"WzUsNl0"}`:                                     "invalid filter: before: must contain 1 values, one per sort column",
+				`{"sort":["password"]}`:                                    "invalid filter: sort: sort by 'password' is not allowed",
+				`{"page":{"page":0,"count":100}}`:                          "invalid filter: page.count: must not exceed 50",
+				`{"unknown":1}`:                                            `invalid filter: $: json: unknown field "unknown"`,
+			}
+			for body, message := range cases {
+				assert.PanicsWithError(t, message, func() { ParseFilter([]byte(body), NewBuilder("users"), schema) }, body)
+			}
+
+			var err error
+			func() {
+				defer func() { err = recover().(error) }()
+				ParseFilter([]byte(`{"sort":["id"]}`), NewBuilder("users"), schema)
+			}()
+			assert.True(t, errors.Is(err, ErrInvalidFilter))
+		},
+	)
+}
@@ -0,0 +1,208 @@
+package query
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/betam/glb/lib/sdk"
+)
+
+const Redacted = "[REDACTED]"
+
+// Event describes a single query execution passed to hooks. Args hold the parameters as is, except those
+// wrapped with Secret, which are replaced by Redacted: wrap every sensitive value, since hooks such as LogHook
+// write the arguments out. Rows holds the affected rows for Exec and the returned rows for Query.
+type Event struct {
+	Query         string
+	Args          []any
+	CorrelationId string
+	Duration      time.Duration
+	Rows          int
+	Slow          bool
+	Err           error
+}
+
+type Hook interface {
+	Before(ctx context.Context, event *Event) context.Context
+	After(ctx context.Context, event *Event)
+}
+
+var (
+	hooksMutex    sync.RWMutex
+	hooks         = []Hook{LogHook{}}
+	slowThreshold time.Duration
+)
+
+func AddHook(hook Hook) {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// SetHooks replaces all registered hooks, including the default LogHook.
+func SetHooks(list ...Hook) {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	hooks = list
+}
+
+// SetSlowThreshold marks executions taking at least the threshold as slow; zero disables the detection.
+func SetSlowThreshold(threshold time.Duration) {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	slowThreshold = threshold
+}
+
+type secret struct {
+	value any
+}
+
+// Secret wraps a parameter so that hooks see it redacted while the driver gets the actual value. Nothing else is
+// redacted automatically.
+func Secret(value any) driver.Valuer {
+	return secret{value}
+}
+
+func (s secret) Value() (driver.Value, error) {
+	if valuer, ok := s.value.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return s.value, nil
+}
+
+func redact(args []any) []any {
+	result := make([]any, len(args))
+	for idx, arg := range args {
+		if _, ok := arg.(secret); ok {
+			result[idx] = Redacted
+		} else {
+			result[idx] = arg
+		}
+	}
+	return result
+}
+
+func observe(ctx context.Context, query string, args []any, execute func(ctx context.Context) (int, error)) (int, error) {
+	hooksMutex.RLock()
+	active, threshold := hooks, slowThreshold
+	hooksMutex.RUnlock()
+
+	event := &Event{Query: query, Args: redact(args)}
+	if session := sdk.SessionFromContext(ctx); session != nil {
+		event.CorrelationId = session.CorrelationId
+	}
+	// Every hook gets the context returned by the previous one, and the query runs with the last of them, so
+	// spans started by hooks reach the driver and nested calls.
+	contexts := make([]context.Context, len(active))
+	for idx, hook := range active {
+		ctx = hook.Before(ctx, event)
+		contexts[idx] = ctx
+	}
+
+	start := time.Now()
+	event.Rows, event.Err = execute(ctx)
+	event.Duration = time.Since(start)
+	event.Slow = threshold > 0 && event.Duration >= threshold
+
+	for idx := len(active) - 1; idx >= 0; idx-- {
+		active[idx].After(contexts[idx], event)
+	}
+	return event.Rows, event.Err
+}
+
+func rowsOf(result any) int {
+	v := reflect.ValueOf(result)
+	if v.Kind() == reflect.Slice {
+		return v.Len()
+	}
+	return 1
+}
+
+// LogHook writes failed executions and slow queries as warnings and everything else at trace level, together
+// with the arguments; only Secret arguments are redacted.
+type LogHook struct{}
+
+func (LogHook) Before(ctx context.Context, _ *Event) context.Context {
+	return ctx
+}
+
+func (LogHook) After(ctx context.Context, event *Event) {
+	entry := logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"args":     event.Args,
+		"duration": event.Duration,
+		"rows":     event.Rows,
+	})
+	if event.CorrelationId != "" {
+		entry = entry.WithField("correlation_id", event.CorrelationId)
+	}
+	switch {
+	case event.Err != nil:
+		entry.WithError(event.Err).Warn(event.Query)
+	case event.Slow:
+		entry.Warnf("slow query: %s", event.Query)
+	default:
+		entry.Trace(event.Query)
+	}
+}
+
+// Span is the subset of an OpenTelemetry span used by TraceHook.
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// Tracer is the subset of an OpenTelemetry tracer used by TraceHook.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type spanKey struct{}
+
+func NewTraceHook(tracer Tracer) *traceHook {
+	return &traceHook{tracer: tracer}
+}
+
+type traceHook struct {
+	tracer Tracer
+}
+
+func (h *traceHook) Before(ctx context.Context, event *Event) context.Context {
+	ctx, span := h.tracer.Start(ctx, spanName(event.Query))
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.statement", event.Query)
+	if event.CorrelationId != "" {
+		span.SetAttribute("correlation_id", event.CorrelationId)
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *traceHook) After(ctx context.Context, event *Event) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.rows_affected", event.Rows)
+	if event.Slow {
+		span.SetAttribute("db.slow", true)
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+	}
+	span.End()
+}
+
+func spanName(query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "sql"
+	}
+	return fmt.Sprintf("sql %s", strings.ToUpper(words[0]))
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sdk"
+)
+
+type recordingHook struct {
+	events []Event
+}
+
+func (h *recordingHook) Before(ctx context.Context, _ *Event) context.Context {
+	return ctx
+}
+
+func (h *recordingHook) After(_ context.Context, event *Event) {
+	h.events = append(h.events, *event)
+}
+
+type testSpan struct {
+	name       string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value any) { s.attributes[key] = value }
+func (s *testSpan) RecordError(err error)              { s.err = err }
+func (s *testSpan) End()                               { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attributes: map[string]any{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestHooks(t *testing.T) {
+	recorder, tracer := &recordingHook{}, &testTracer{}
+	SetHooks(recorder, NewTraceHook(tracer))
+	SetSlowThreshold(time.Millisecond)
+	defer func() {
+		SetHooks(LogHook{})
+		SetSlowThreshold(0)
+	}()
+
+	ctx := sdk.NewContextWithSession(context.Background(), sdk.Session{CorrelationId: "cid"})
+	Query[[]int](ctx, func(ctx context.Context, dest any, query string, args ...any) error {
+		value, err := args[1].(driver.Valuer).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "password", value)
+		assert.Same(t, tracer.spans[0], ctx.Value(spanKey{}))
+		*dest.(*[]int) = []int{1, 2, 3}
+		return nil
+	}, NewBuilder("users").Select("id").Where(And("name", "eq", "user").Add("password", "eq", Secret("password"))).NotSort())
+
+	Exec(ctx, func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+		time.Sleep(2 * time.Millisecond)
+		return driverResult{}, nil
+	}, NewBuilder("users").Delete())
+
+	assert.Panics(t, func() {
+		Exec(ctx, func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+			return nil, fmt.Errorf("failed")
+		}, NewBuilder("users").Delete())
+	})
+
+	assert.Len(t, recorder.events, 3)
+	assert.Equal(t, "select id from users where (name = $1) and (password = $2)", recorder.events[0].Query)
+	assert.Equal(t, []any{"user", Redacted}, recorder.events[0].Args)
+	assert.Equal(t, 3, recorder.events[0].Rows)
+	assert.Equal(t, "cid", recorder.events[0].CorrelationId)
+	assert.False(t, recorder.events[0].Slow)
+	assert.Equal(t, 2, recorder.events[1].Rows)
+	assert.True(t, recorder.events[1].Slow)
+	assert.EqualError(t, recorder.events[2].Err, "failed")
+
+	assert.Len(t, tracer.spans, 3)
+	assert.Equal(t, "sql SELECT", tracer.spans[0].name)
+	assert.Equal(t, "cid", tracer.spans[0].attributes["correlation_id"])
+	assert.Equal(t, 3, tracer.spans[0].attributes["db.rows_affected"])
+	assert.Equal(t, true, tracer.spans[1].attributes["db.slow"])
+	assert.EqualError(t, tracer.spans[2].err, "failed")
+	assert.True(t, tracer.spans[2].ended)
+}
@@ -0,0 +1,211 @@
+package query
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+type structColumn struct {
+	name      string
+	index     []int
+	omitempty bool
+	readonly  bool
+}
+
+var (
+	structColumnsCache sync.Map
+	valuerType         = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+func structColumns(t reflect.Type) []structColumn {
+	if columns, ok := structColumnsCache.Load(t); ok {
+		return columns.([]structColumn)
+	}
+	columns := collectStructColumns(t, nil)
+	structColumnsCache.Store(t, columns)
+	return columns
+}
+
+func collectStructColumns(t reflect.Type, parent []int) []structColumn {
+	var columns []structColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag := strings.Split(field.Tag.Get("db"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if field.Anonymous && tag[0] == "" && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, collectStructColumns(field.Type, index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		column := structColumn{name: tag[0], index: index}
+		if column.name == "" {
+			column.name = strings.ToLower(field.Name)
+		}
+		for _, option := range tag[1:] {
+			switch option {
+			case "omitempty":
+				column.omitempty = true
+			case "readonly":
+				column.readonly = true
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+func isStructRow(value any) bool {
+	return value != nil && isStructType(reflect.TypeOf(value))
+}
+
+// isStructType reports whether values of t are mapped by their db tags rather than bound as a single parameter.
+func isStructType(t reflect.Type) bool {
+	if t.Implements(valuerType) || t == reflect.TypeOf(&raw{}) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !t.ConvertibleTo(reflect.TypeOf(time.Time{}))
+}
+
+func structValue(value any) reflect.Value {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			panic(fmt.Errorf("cannot map nil %s", v.Type()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func structFieldByColumn(v reflect.Value, name string) reflect.Value {
+	for _, column := range structColumns(v.Type()) {
+		if column.name == name {
+			return v.FieldByIndex(column.index)
+		}
+	}
+	return reflect.Value{}
+}
+
+// structRows maps structs to insert rows: readonly columns are skipped, and zero omitempty columns fall back
+// to the column default, or are dropped when zero for every row.
+func structRows(fields []string, structs []any) ([]string, [][]any) {
+	values := make([]reflect.Value, 0, len(structs))
+	for _, s := range structs {
+		values = append(values, structValue(s))
+	}
+	t := values[0].Type()
+	for _, v := range values {
+		if v.Type() != t {
+			panic(fmt.Errorf("cannot mix %s and %s in insert values", t, v.Type()))
+		}
+	}
+
+	var columns []structColumn
+	if len(fields) == 0 {
+		for _, column := range structColumns(t) {
+			if column.readonly {
+				continue
+			}
+			if column.omitempty {
+				empty := true
+				for _, v := range values {
+					empty = empty && v.FieldByIndex(column.index).IsZero()
+				}
+				if empty {
+					continue
+				}
+			}
+			columns = append(columns, column)
+			fields = append(fields, column.name)
+		}
+	} else {
+		for _, field := range fields {
+			found := false
+			for _, column := range structColumns(t) {
+				if column.name == field {
+					columns = append(columns, column)
+					found = true
+					break
+				}
+			}
+			if !found {
+				panic(fmt.Errorf("field '%s' not found in %s", field, t))
+			}
+		}
+	}
+
+	rows := make([][]any, 0, len(values))
+	for _, v := range values {
+		row := make([]any, 0, len(columns))
+		for _, column := range columns {
+			field := v.FieldByIndex(column.index)
+			if column.omitempty && field.IsZero() {
+				row = append(row, Raw("default"))
+			} else {
+				row = append(row, field.Interface())
+			}
+		}
+		rows = append(rows, row)
+	}
+	return fields, rows
+}
+
+func structAssignments(value any) []*assignment {
+	v := structValue(value)
+	if v.Kind() != reflect.Struct {
+		panic(fmt.Errorf("cannot update from %s", v.Type()))
+	}
+	var assignments []*assignment
+	for _, column := range structColumns(v.Type()) {
+		field := v.FieldByIndex(column.index)
+		if column.readonly || column.omitempty && field.IsZero() {
+			continue
+		}
+		assignments = append(assignments, &assignment{field: column.name, value: field.Interface()})
+	}
+	return assignments
+}
+
+// Rows normalizes insert values the same way as InsertBuilder.Values and returns the resulting columns and rows.
+func Rows(fields []string, values ...any) ([]string, [][]any) {
+	b := &builder{}
+	b.Values(values...)
+	if b.structs != nil {
+		return structRows(fields, b.structs)
+	}
+	for _, row := range b.inserts {
+		if len(row) != len(fields) {
+			panic(fmt.Errorf("wrong insert value (count of fields not match count of values)"))
+		}
+	}
+	return fields, b.inserts
+}
+
+// ScopeRows sets the insert values of the scopes active for the table on the rows, as Exec does for insert
+// builders, so that writers bypassing the builder store them too.
+func ScopeRows(ctx context.Context, table string, fields []string, rows [][]any) ([]string, [][]any) {
+	b := NewBuilder(table).Insert(fields...).(*builder)
+	b.scoped(ctx)
+	return b.scopeRows(fields, rows)
+}
+
+func FieldValue(value any, column string) any {
+	field := structFieldByColumn(structValue(value), column)
+	if !field.IsValid() {
+		panic(fmt.Errorf("field '%s' not found in %T", column, value))
+	}
+	return field.Interface()
+}
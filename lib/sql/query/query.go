@@ -3,9 +3,9 @@ package query
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/betam/glb/lib/try"
-	"github.com/sirupsen/logrus"
 )
 
 type selectHandler func(ctx context.Context, dest any, query string, args ...any) error
@@ -15,14 +15,20 @@ type execHandler func(ctx context.Context, query string, args ...any) (sql.Resul
 type execNamedHandler func(ctx context.Context, query string, arg any) (sql.Result, error)
 
 func Query[Result any](ctx context.Context, handler selectHandler, builder Builder, dest ...*Result) (result Result) {
-	query, args := builder.Returning("*").Build()
+	query, args := builder.scoped(ctx).Returning("*").Build()
 
-	//logrus.Trace(query, *args)
-
-	err := handler(ctx, &result, query, *args...)
-	if err != nil {
-		logrus.Warn(query, *args)
-		try.ThrowError(err)
+	_, err := observe(ctx, query, *args, func(ctx context.Context) (int, error) {
+		if err := handler(ctx, &result, query, *args...); err != nil {
+			return 0, err
+		}
+		return rowsOf(result), nil
+	})
+	if stale := builder.stale(); stale != nil && errors.Is(err, sql.ErrNoRows) {
+		panic(stale)
+	}
+	try.ThrowError(err)
+	if stale := builder.stale(); stale != nil && rowsOf(result) == 0 {
+		panic(stale)
 	}
 	if len(dest) == 1 {
 		*dest[0] = result
@@ -31,23 +37,33 @@ func Query[Result any](ctx context.Context, handler selectHandler, builder Build
 }
 
 func Exec(ctx context.Context, handler execHandler, builder Builder) int {
-	query, args := builder.Build()
+	query, args := builder.scoped(ctx).Build()
 
-	result, err := handler(ctx, query, *args...)
-	if err != nil {
-		logrus.Warn(query, *args)
-		try.ThrowError(err)
+	rows := try.Throw(observe(ctx, query, *args, func(ctx context.Context) (int, error) {
+		return affected(handler(ctx, query, *args...))
+	}))
+	if stale := builder.stale(); stale != nil && rows == 0 {
+		panic(stale)
 	}
-	return int(try.Throw(result.RowsAffected()))
+	return rows
 }
 
 func ExecNamed(ctx context.Context, handler execNamedHandler, builder Builder) int {
-	query, args := builder.named(true).Build()
+	query, args := builder.scoped(ctx).named(true).Build()
+
+	rows := try.Throw(observe(ctx, query, *args, func(ctx context.Context) (int, error) {
+		return affected(handler(ctx, query, *args))
+	}))
+	if stale := builder.stale(); stale != nil && rows == 0 {
+		panic(stale)
+	}
+	return rows
+}
 
-	result, err := handler(ctx, query, *args)
+func affected(result sql.Result, err error) (int, error) {
 	if err != nil {
-		logrus.Warn(query, *args)
-		try.ThrowError(err)
+		return 0, err
 	}
-	return int(try.Throw(result.RowsAffected()))
+	rows, err := result.RowsAffected()
+	return int(rows), err
 }
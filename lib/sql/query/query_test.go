@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -194,3 +195,60 @@ func TestExecNamed(t *testing.T) {
 		},
 	)
 }
+
+type noRowsResult struct {
+	driverResult
+}
+
+func (noRowsResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+func TestVersioned(t *testing.T) {
+	t.Run(
+		"Build",
+		func(t *testing.T) {
+			type row struct {
+				Id      int    `db:"id,readonly"`
+				Name    string `db:"name"`
+				Version int    `db:"version"`
+			}
+			sql, params := NewBuilder("test").UpdateStruct(row{Id: 1, Name: "a", Version: 3}).Versioned("version", 3).Where(And("id", "eq", 1)).Build()
+			assert.Equal(t, "update test set name=$1, version=version + 1 where (id = $2) and (version = $3)", sql)
+			assert.Equal(t, &[]any{"a", 1, 3}, params)
+
+			sql, params = NewBuilder("test").Update(map[string]any{"a": 1}).Versioned("version", 3).Where(Or(And("id", "eq", 1), And("id", "eq", 2))).Build()
+			assert.Equal(t, "update test set a=$1, version=version + 1 where (((id = $2)) or ((id = $3))) and (version = $4)", sql)
+			assert.Equal(t, &[]any{1, 1, 2, 3}, params)
+		},
+	)
+
+	t.Run(
+		"Exec",
+		func(t *testing.T) {
+			handler := func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+				return noRowsResult{}, nil
+			}
+			assert.Equal(t, 0, Exec(context.Background(), handler, NewBuilder("test").Update(map[string]any{"a": 1})))
+
+			defer func() {
+				err, _ := recover().(error)
+				assert.True(t, errors.Is(err, ErrStaleVersion))
+				assert.EqualError(t, err, "stale version: test.version is not 3")
+			}()
+			Exec(context.Background(), handler, NewBuilder("test").Update(map[string]any{"a": 1}).Versioned("version", 3))
+		},
+	)
+
+	t.Run(
+		"Query",
+		func(t *testing.T) {
+			handler := func(ctx context.Context, dest any, query string, args ...any) error {
+				return fmt.Errorf("wrapped: %w", sql.ErrNoRows)
+			}
+			assert.PanicsWithError(t, "stale version: test.version is not 3", func() {
+				Query[int](context.Background(), handler, NewBuilder("test").Update(map[string]any{"a": 1}).Versioned("version", 3))
+			})
+		},
+	)
+}
@@ -7,3 +7,12 @@ type raw struct {
 func Raw(expression string) *raw {
 	return &raw{expression}
 }
+
+func Column(name string) *raw {
+	return Raw(name)
+}
+
+func IsRaw(value any) bool {
+	_, ok := value.(*raw)
+	return ok
+}
@@ -0,0 +1,163 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/betam/glb/lib/list"
+)
+
+const (
+	ScopeSoftDelete = "soft_delete"
+	ScopeTenant     = "tenant"
+)
+
+var ErrTenantRequired = fmt.Errorf("tenant is required")
+
+// Scope restricts queries built through Query, Exec, ExecNamed, Each and sql.Stream. It applies to the listed
+// tables only, wherever they appear: the main table, joins and subqueries; builders opt out with Unscoped. Raw
+// table, join and field strings referencing a scoped table panic, since the scope cannot be applied to them.
+type Scope struct {
+	Name   string
+	Tables []string
+	// Condition is appended to select, update and delete queries; alias qualifies the columns of the scoped
+	// table and is empty when the query has no alias. A nil expression skips the scope.
+	Condition func(ctx context.Context, alias string) Expression
+	// Values are set on inserted rows, overwriting explicitly listed columns, and on the same columns of updates.
+	Values func(ctx context.Context) map[string]any
+}
+
+var (
+	scopesMutex sync.RWMutex
+	scopes      []Scope
+)
+
+// AddScope registers the scope; it panics when the scope lists no tables.
+func AddScope(scope Scope) {
+	validateScope(scope)
+	scopesMutex.Lock()
+	defer scopesMutex.Unlock()
+	scopes = append(scopes, scope)
+}
+
+func SetScopes(list ...Scope) {
+	for _, scope := range list {
+		validateScope(scope)
+	}
+	scopesMutex.Lock()
+	defer scopesMutex.Unlock()
+	scopes = list
+}
+
+func validateScope(scope Scope) {
+	if len(scope.Tables) == 0 {
+		panic(fmt.Errorf("scope '%s' requires at least one table", scope.Name))
+	}
+}
+
+// Scoped applies the scopes of the context to the next Build of the builder, as Query and Exec do.
+func Scoped(ctx context.Context, builder Builder) Builder {
+	return builder.scoped(ctx)
+}
+
+func activeScopes(table string, unscoped []string) []Scope {
+	scopesMutex.RLock()
+	defer scopesMutex.RUnlock()
+	var result []Scope
+	for _, scope := range scopes {
+		if len(list.Filter(scope.Tables, func(t string) bool { return table == t || strings.HasSuffix(table, "."+t) })) == 0 {
+			continue
+		}
+		if unscoped != nil && (len(unscoped) == 0 || list.Contains(scope.Name, unscoped)) {
+			continue
+		}
+		result = append(result, scope)
+	}
+	return result
+}
+
+var (
+	plainTable     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+	leadingTable   = regexp.MustCompile(`^\s*\(?\s*([A-Za-z_][A-Za-z0-9_.]*)`)
+	referenceTable = regexp.MustCompile(`(?i)\b(?:from|join)\s+([A-Za-z_][A-Za-z0-9_.]*)`)
+)
+
+// checkRaw panics when raw sql references a table with active scopes, since the scopes cannot be applied to it
+// and the query would fail open. The leading identifier of table strings is a reference as well.
+func checkRaw(text string, table bool, unscoped []string) {
+	if text == "" || table && plainTable.MatchString(text) {
+		return
+	}
+	var references []string
+	if table {
+		if match := leadingTable.FindStringSubmatch(text); match != nil {
+			references = append(references, match[1])
+		}
+	}
+	for _, match := range referenceTable.FindAllStringSubmatch(text, -1) {
+		references = append(references, match[1])
+	}
+	for _, reference := range references {
+		if active := activeScopes(reference, unscoped); len(active) > 0 {
+			panic(fmt.Errorf(
+				"raw sql '%s' references table '%s' of scope '%s': use the builder for the table or opt out with Unscoped",
+				text, reference, active[0].Name,
+			))
+		}
+	}
+}
+
+func qualify(alias string, column string) string {
+	if alias == "" {
+		return column
+	}
+	return fmt.Sprintf("%s.%s", alias, column)
+}
+
+// SoftDelete hides rows with a non-null column from select, update and delete queries.
+func SoftDelete(column string, tables ...string) Scope {
+	return Scope{
+		Name:   ScopeSoftDelete,
+		Tables: tables,
+		Condition: func(ctx context.Context, alias string) Expression {
+			return And(qualify(alias, column), "eq", nil)
+		},
+	}
+}
+
+// Tenant limits queries to the rows of the current tenant and stores it on inserts and updates. The tenant is
+// taken from NewContextWithTenant; queries without a tenant panic with ErrTenantRequired.
+func Tenant(column string, tables ...string) Scope {
+	tenant := func(ctx context.Context) any {
+		value := TenantFromContext(ctx)
+		if value == nil {
+			panic(fmt.Errorf("%w: column '%s'", ErrTenantRequired, column))
+		}
+		return value
+	}
+	return Scope{
+		Name:   ScopeTenant,
+		Tables: tables,
+		Condition: func(ctx context.Context, alias string) Expression {
+			return And(qualify(alias, column), "eq", tenant(ctx))
+		},
+		Values: func(ctx context.Context) map[string]any {
+			return map[string]any{column: tenant(ctx)}
+		},
+	}
+}
+
+type tenantKey struct{}
+
+// NewContextWithTenant sets the tenant for the Tenant scope. Call it with the tenant of the authenticated
+// principal, never with a value taken from the request as is.
+func NewContextWithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) any {
+	return ctx.Value(tenantKey{})
+}
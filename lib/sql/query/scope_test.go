@@ -0,0 +1,162 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sdk"
+)
+
+func TestScopes(t *testing.T) {
+	SetScopes(SoftDelete("deleted_at", "users", "orders"), Tenant("tenant_id", "users"))
+	defer SetScopes()
+
+	var queries []string
+	var params [][]any
+	exec := func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+		queries, params = append(queries, query), append(params, args)
+		return driverResult{}, nil
+	}
+	ctx := NewContextWithTenant(context.Background(), 7)
+
+	t.Run(
+		"Conditions",
+		func(t *testing.T) {
+			queries, params = nil, nil
+			Exec(ctx, exec, NewBuilder("users").Update(map[string]any{"name": "a"}).Where(And("id", "eq", 1)))
+			Exec(ctx, exec, NewBuilder("users").Delete())
+			Exec(ctx, exec, NewBuilder("users").Select("*").(SelectBuilder).JoinOn("left", "orders", "o", And("o.user_id", "eq", Column("maintbl.id"))).NotSort())
+			Exec(ctx, exec, NewBuilder("items").Delete())
+			Exec(ctx, exec, NewBuilder("items").Select("*").(SelectBuilder).Join("inner", "orders", "o", "o.id = maintbl.order_id or o.id is null").NotSort())
+			assert.Equal(t, []string{
+				"update users set name=$1 where (id = $2) and (deleted_at is null) and (tenant_id = $3)",
+				"delete from users where (deleted_at is null) and (tenant_id = $1)",
+				"select * from users AS maintbl left JOIN orders AS o ON (o.user_id = maintbl.id) and (o.deleted_at is null) where (maintbl.deleted_at is null) and (maintbl.tenant_id = $1)",
+				"delete from items",
+				"select * from items AS maintbl inner JOIN orders AS o ON (o.id = maintbl.order_id or o.id is null) and (o.deleted_at is null)",
+			}, queries)
+			assert.Equal(t, []any{"a", 1, 7}, params[0])
+		},
+	)
+
+	t.Run(
+		"Or",
+		func(t *testing.T) {
+			queries, params = nil, nil
+			or := Or(And("id", "eq", 1), And("id", "eq", 2))
+			Exec(ctx, exec, NewBuilder("users").Select("*").Where(or).NotSort())
+			Exec(ctx, exec, NewBuilder("users").Update(map[string]any{"name": "a"}).Where(or))
+			Exec(ctx, exec, NewBuilder("users").Delete().Where(or))
+			Exec(ctx, exec, NewBuilder("items").Select("*").(SelectBuilder).JoinOn("inner", "orders", "o", Or(And("o.id", "eq", Column("maintbl.order_id")), And("o.user_id", "eq", Column("maintbl.user_id")))).NotSort())
+			assert.Equal(t, []string{
+				"select * from users where (((id = $1)) or ((id = $2))) and (deleted_at is null) and (tenant_id = $3)",
+				"update users set name=$1 where (((id = $2)) or ((id = $3))) and (deleted_at is null) and (tenant_id = $4)",
+				"delete from users where (((id = $1)) or ((id = $2))) and (deleted_at is null) and (tenant_id = $3)",
+				"select * from items AS maintbl inner JOIN orders AS o ON (((o.id = maintbl.order_id)) or ((o.user_id = maintbl.user_id))) and (o.deleted_at is null)",
+			}, queries)
+		},
+	)
+
+	t.Run(
+		"Raw",
+		func(t *testing.T) {
+			message := "raw sql 'orders o join items i on i.order_id = o.id' references table 'orders' of scope 'soft_delete': " +
+				"use the builder for the table or opt out with Unscoped"
+			assert.PanicsWithError(t, message, func() {
+				Exec(ctx, exec, NewBuilder("orders o join items i on i.order_id = o.id").Delete())
+			})
+			assert.PanicsWithError(
+				t,
+				"raw sql 'o.id = maintbl.order_id and exists (select 1 from users u where u.id = o.user_id)' references table 'users' of scope 'soft_delete': "+
+					"use the builder for the table or opt out with Unscoped",
+				func() {
+					Exec(ctx, exec, NewBuilder("items").Select("*").(SelectBuilder).Join("inner", "payments", "o", "o.id = maintbl.order_id and exists (select 1 from users u where u.id = o.user_id)").NotSort())
+				},
+			)
+			assert.Panics(t, func() {
+				Exec(ctx, exec, NewBuilder("items").Select("*, (select count(*) from public.orders where item_id = items.id) as n").NotSort())
+			})
+
+			queries, params = nil, nil
+			Exec(ctx, exec, NewBuilder("orders o join items i on i.order_id = o.id").Delete().Unscoped(ScopeSoftDelete))
+			Exec(ctx, exec, NewBuilder("public.orders").Delete())
+			assert.Equal(t, []string{
+				"delete from orders o join items i on i.order_id = o.id",
+				"delete from public.orders where (deleted_at is null)",
+			}, queries)
+		},
+	)
+
+	t.Run(
+		"Subqueries",
+		func(t *testing.T) {
+			queries, params = nil, nil
+			Exec(ctx, exec, NewBuilder("items").Delete().Where(And("user_id", "in", NewBuilder("users").Select("id").NotSort())))
+			Exec(ctx, exec, NewBuilder("items").Delete().Where(Or(Exists(NewBuilder("orders").Select("1").Where(And("orders.item_id", "eq", Column("items.id"))).NotSort()))))
+			assert.Equal(t, []string{
+				"delete from items where (user_id in (select id from users where (deleted_at is null) and (tenant_id = $1)))",
+				"delete from items where (exists (select 1 from orders where (orders.item_id = items.id) and (deleted_at is null)))",
+			}, queries)
+			assert.Equal(t, []any{7}, params[0])
+		},
+	)
+
+	t.Run(
+		"Insert",
+		func(t *testing.T) {
+			queries, params = nil, nil
+			builder := NewBuilder("users").Insert("name").Values([]any{"a"}, []any{"b"})
+			Exec(ctx, exec, builder)
+			Exec(ctx, exec, NewBuilder("users").Insert("name", "tenant_id").Values("a", 3))
+			Exec(ctx, exec, NewBuilder("users").Update(map[string]any{"tenant_id": 3}).Where(And("id", "eq", 1)))
+			assert.Equal(t, []string{
+				"insert into users (name, tenant_id) values ($1, $2), ($3, $4)",
+				"insert into users (name, tenant_id) values ($1, $2)",
+				"update users set tenant_id=$1 where (id = $2) and (deleted_at is null) and (tenant_id = $3)",
+			}, queries)
+			assert.Equal(t, []any{"a", 7, "b", 7}, params[0])
+			assert.Equal(t, []any{"a", 7}, params[1])
+			assert.Equal(t, []any{7, 1, 7}, params[2])
+
+			assert.PanicsWithError(
+				t,
+				"scoped columns cannot be set on named or insert from select queries, use Unscoped to set them explicitly",
+				func() {
+					Exec(ctx, exec, NewBuilder("users").Insert("name").SubTable(NewBuilder("items").Select("name").NotSort()))
+				},
+			)
+
+			sql, _ := builder.Build()
+			assert.Equal(t, "insert into users (name) values ($1), ($2)", sql)
+		},
+	)
+
+	t.Run(
+		"Unscoped",
+		func(t *testing.T) {
+			queries, params = nil, nil
+			Exec(ctx, exec, NewBuilder("users").Delete().Unscoped(ScopeSoftDelete))
+			Exec(context.Background(), exec, NewBuilder("users").Delete().Unscoped())
+			assert.Equal(t, []string{"delete from users where (tenant_id = $1)", "delete from users"}, queries)
+		},
+	)
+
+	t.Run(
+		"Tenant",
+		func(t *testing.T) {
+			assert.Equal(t, 7, TenantFromContext(NewContextWithTenant(context.Background(), 7)))
+			assert.Nil(t, TenantFromContext(sdk.NewContextWithSession(context.Background(), sdk.Session{CorrelationId: "cid"})))
+			assert.PanicsWithError(t, "scope 'tenant' requires at least one table", func() { AddScope(Tenant("tenant_id")) })
+
+			defer func() {
+				err, _ := recover().(error)
+				assert.True(t, errors.Is(err, ErrTenantRequired))
+			}()
+			Exec(context.Background(), exec, NewBuilder("users").Delete())
+		},
+	)
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type Condition struct {
+	Field     string
+	Operation string
+	Value     any
+}
+
+type Visitor interface {
+	Enter(mode string, negated bool) bool
+	Condition(condition Condition)
+	Leave(mode string, negated bool)
+}
+
+func Walk(expr Expression, visitor Visitor) {
+	e := unwrap(expr)
+	if !visitor.Enter(e.mode(), e.negate) {
+		return
+	}
+	for _, part := range e.parts {
+		if nested, ok := part.(Expression); ok {
+			Walk(nested, visitor)
+		} else {
+			visitor.Condition(condition(part))
+		}
+	}
+	visitor.Leave(e.mode(), e.negate)
+}
+
+// Transform returns a copy of the expression where every condition is replaced by the result of the callback:
+// a Condition, an Expression or nil to drop the condition.
+func Transform(expr Expression, callback func(condition Condition) any) Expression {
+	e := unwrap(expr)
+	result := &expression{strategy: e.strategy, parts: []any{}, negate: e.negate}
+	for _, part := range e.parts {
+		if nested, ok := part.(Expression); ok {
+			result.parts = append(result.parts, Transform(nested, callback))
+			continue
+		}
+		switch replacement := callback(condition(part)).(type) {
+		case nil:
+		case Condition:
+			result.parts = append(result.parts, []any{replacement.Field, replacement.Operation, replacement.Value})
+		case *Condition:
+			result.parts = append(result.parts, []any{replacement.Field, replacement.Operation, replacement.Value})
+		case Expression:
+			result.parts = append(result.parts, replacement)
+		default:
+			panic(fmt.Errorf("unsupported transform result: %T", replacement))
+		}
+	}
+	return result
+}
+
+// Simplify returns a canonical copy of the expression: empty groups are dropped, single-part groups are unwrapped
+// and nested groups of the same mode are flattened.
+func Simplify(expr Expression) Expression {
+	if simplified := simplify(unwrap(expr)); simplified != nil {
+		return simplified
+	}
+	return And()
+}
+
+func simplify(e *expression) *expression {
+	result := &expression{strategy: e.mode(), parts: []any{}, negate: e.negate}
+	for _, part := range e.parts {
+		nested, ok := part.(Expression)
+		if !ok {
+			result.parts = append(result.parts, part)
+			continue
+		}
+		child := simplify(unwrap(nested))
+		if child == nil {
+			continue
+		}
+		if !child.negate && (child.mode() == result.mode() || len(child.parts) == 1) {
+			result.parts = append(result.parts, child.parts...)
+		} else {
+			result.parts = append(result.parts, child)
+		}
+	}
+	if len(result.parts) == 0 {
+		return nil
+	}
+	if len(result.parts) == 1 && !result.negate {
+		if child, ok := result.parts[0].(*expression); ok {
+			return child
+		}
+	}
+	return result
+}
+
+func unwrap(e Expression) *expression {
+	return e.(*expression)
+}
+
+func condition(part any) Condition {
+	v := reflect.ValueOf(part)
+	return Condition{
+		Field:     v.Index(0).Elem().String(),
+		Operation: v.Index(1).Elem().String(),
+		Value:     v.Index(2).Interface(),
+	}
+}
+
+func (e *expression) mode() string {
+	if e.strategy == "" {
+		return strategyAnd
+	}
+	return e.strategy
+}
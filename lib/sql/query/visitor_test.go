@@ -0,0 +1,86 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	trace []string
+	skip  string
+}
+
+func (r *recorder) Enter(mode string, negated bool) bool {
+	r.trace = append(r.trace, fmt.Sprintf("enter %s %v", mode, negated))
+	return mode != r.skip
+}
+
+func (r *recorder) Condition(condition Condition) {
+	r.trace = append(r.trace, fmt.Sprintf("%s %s %v", condition.Field, condition.Operation, condition.Value))
+}
+
+func (r *recorder) Leave(mode string, negated bool) {
+	r.trace = append(r.trace, fmt.Sprintf("leave %s %v", mode, negated))
+}
+
+func TestVisitor(t *testing.T) {
+	t.Run(
+		"Walk",
+		func(t *testing.T) {
+			expr := And("a", "eq", 1).Add(Not(Or("b", "gt", 2).Add("c", "lt", 3)))
+			r := &recorder{}
+			Walk(expr, r)
+			assert.Equal(t, []string{"enter and false", "a eq 1", "enter or true", "b gt 2", "c lt 3", "leave or true", "leave and false"}, r.trace)
+
+			r = &recorder{skip: "or"}
+			Walk(expr, r)
+			assert.Equal(t, []string{"enter and false", "a eq 1", "enter or true", "leave and false"}, r.trace)
+		},
+	)
+
+	t.Run(
+		"Transform",
+		func(t *testing.T) {
+			expr := And("name", "eq", "a").Add(Or("age", "gt", 18).Add("internal", "eq", true))
+			transformed := Transform(expr, func(condition Condition) any {
+				if condition.Field == "internal" {
+					return nil
+				}
+				if condition.Field == "age" {
+					return And("age", "gt", condition.Value).Add("age", "lt", 65)
+				}
+				condition.Field = "u." + condition.Field
+				return condition
+			})
+			sql, params := And("tenant_id", "eq", 5).Add(transformed).Build()
+			assert.Equal(t, "(tenant_id = $1) and ((u.name = $2) and (((age > $3) and (age < $4))))", sql)
+			assert.Equal(t, &[]any{5, "a", 18, 65}, params)
+
+			sql, _ = expr.Build()
+			assert.Equal(t, "(name = $1) and ((age > $2) or (internal = $3))", sql)
+		},
+	)
+
+	t.Run(
+		"Simplify",
+		func(t *testing.T) {
+			sql, params := Simplify(And(And("a", "eq", 1), And(), Or(And("b", "eq", 2)), Or("c", "eq", 3).Add("d", "eq", 4))).Build()
+			assert.Equal(t, "(a = $1) and (b = $2) and ((c = $3) or (d = $4))", sql)
+			assert.Equal(t, &[]any{1, 2, 3, 4}, params)
+
+			sql, _ = Simplify(And(Not(And("a", "eq", 1)), And(And()))).Build()
+			assert.Equal(t, "not ((a = $1))", sql)
+
+			sql, params = Simplify(Or(And(), And())).Build()
+			assert.Equal(t, "", sql)
+			assert.Empty(t, *params)
+
+			q, _ := NewBuilder("test").Where(And()).NotSort().Build()
+			assert.Equal(t, "select * from test", q)
+			sql, _ = And(And()).Add("a", "eq", 1).Build()
+			assert.Equal(t, "(a = $1)", sql)
+		},
+	)
+}
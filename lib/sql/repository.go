@@ -14,11 +14,12 @@ type Repository interface {
 }
 
 func NewRepository(connection Db) *repository {
-	return &repository{DB: connection}
+	return &repository{DB: connection, Executor: NewExecutor(connection)}
 }
 
 type repository struct {
-	DB Db
+	DB       Db
+	Executor Executor
 }
 
 func (r *repository) Begin(ctx context.Context) (context.Context, func(), func()) {
@@ -0,0 +1,78 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"time"
+
+	"github.com/betam/glb/lib/try"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = 50 * time.Millisecond
+)
+
+type TransactionOptions struct {
+	Isolation dbsql.IsolationLevel
+	ReadOnly  bool
+	// Retries limits the attempts after the first one; zero means 3 retries and a negative value disables them.
+	Retries int
+	Backoff time.Duration
+}
+
+// InTransaction runs the callback in a transaction (or a savepoint inside the ambient one), commits when it
+// returns nil and rolls back on error or panic. Serialization failures and deadlocks of the outermost
+// transaction are retried with exponential backoff.
+func InTransaction(ctx context.Context, connection Db, opts *TransactionOptions, callback func(ctx context.Context) error) error {
+	if opts == nil {
+		opts = &TransactionOptions{}
+	}
+	retries := opts.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	nested := TransactionFromContext(ctx) != nil
+
+	for attempt := 0; ; attempt++ {
+		err := runTransaction(ctx, connection, opts, callback)
+		if err == nil || nested || attempt >= retries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func runTransaction(ctx context.Context, connection Db, opts *TransactionOptions, callback func(ctx context.Context) error) (err error) {
+	try.Catch(
+		func() {
+			childCtx, _, commit, rollback := NewContextWithTransactionOptions(ctx, connection, &dbsql.TxOptions{
+				Isolation: opts.Isolation,
+				ReadOnly:  opts.ReadOnly,
+			})
+			defer rollback()
+			if err = callback(childCtx); err != nil {
+				return
+			}
+			commit()
+		},
+		func(throwable error) {
+			err = throwable
+		},
+	)
+	return err
+}
+
+func retryable(err error) bool {
+	return errors.Is(Classify(err), ErrSerialization)
+}
@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+type stateError struct {
+	state string
+}
+
+func (e stateError) Error() string {
+	return "state " + e.state
+}
+
+func (e stateError) SQLState() string {
+	return e.state
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(stateError{"40001"}))
+	assert.True(t, retryable(fmt.Errorf("wrapped: %w", stateError{"40P01"})))
+	assert.False(t, retryable(stateError{"23505"}))
+	assert.False(t, retryable(fmt.Errorf("something went wrong")))
+}
+
+func TestInTransaction(t *testing.T) {
+	t.Run(
+		"RetryStatement",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On("^delete").Error(stateError{"40001"}).Once()
+
+			attempts := 0
+			err := InTransaction(context.Background(), db, &TransactionOptions{Backoff: 1}, func(ctx context.Context) error {
+				attempts++
+				query.Exec(ctx, NewExecutor(db).ExecContext, query.NewBuilder("users").Delete())
+				return nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 2, attempts)
+			assert.Equal(t, []string{"begin", "rollback", "begin", "commit"}, db.Transactions())
+		},
+	)
+
+	t.Run(
+		"RetryCommit",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On("^commit$").Error(stateError{"40001"}).Once()
+
+			attempts := 0
+			err := InTransaction(context.Background(), db, nil, func(ctx context.Context) error {
+				attempts++
+				return nil
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 2, attempts)
+			assert.Equal(t, []string{"begin", "commit", "begin", "commit"}, db.Transactions())
+		},
+	)
+
+	t.Run(
+		"Limits",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On("^commit$").Error(stateError{"40P01"})
+
+			attempts := 0
+			err := InTransaction(context.Background(), db, &TransactionOptions{Backoff: 1}, func(ctx context.Context) error {
+				attempts++
+				return nil
+			})
+			assert.Equal(t, stateError{"40P01"}, err)
+			assert.Equal(t, 4, attempts)
+
+			attempts = 0
+			err = InTransaction(context.Background(), db, &TransactionOptions{Retries: -1}, func(ctx context.Context) error {
+				attempts++
+				return nil
+			})
+			assert.Equal(t, stateError{"40P01"}, err)
+			assert.Equal(t, 1, attempts)
+		},
+	)
+
+	t.Run(
+		"Rollback",
+		func(t *testing.T) {
+			db := sqltest.New()
+
+			err := InTransaction(context.Background(), db, nil, func(ctx context.Context) error {
+				return fmt.Errorf("failed")
+			})
+			assert.EqualError(t, err, "failed")
+
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			defer rollback()
+			attempts := 0
+			err = InTransaction(ctx, db, nil, func(ctx context.Context) error {
+				attempts++
+				panic(stateError{"40001"})
+			})
+			assert.Equal(t, stateError{"40001"}, err)
+			assert.Equal(t, 1, attempts)
+			commit()
+
+			assert.Equal(t, []string{"begin", "rollback", "begin", "savepoint sp_1", "rollback to savepoint sp_1", "commit"}, db.Transactions())
+		},
+	)
+}
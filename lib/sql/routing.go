@@ -0,0 +1,131 @@
+package sql
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type primaryKey struct{}
+
+type reader interface {
+	Read(ctx context.Context) *sqlx.DB
+}
+
+type connectNotifier interface {
+	OnConnect(callback func(*sqlx.DB))
+}
+
+type healthChecker interface {
+	Healthy() bool
+}
+
+// WithPrimary forces reads executed with the returned context to the primary database.
+func WithPrimary(ctx context.Context) context.Context {
+	flag := &atomic.Bool{}
+	flag.Store(true)
+	return context.WithValue(ctx, primaryKey{}, flag)
+}
+
+// WithStickyPrimary routes reads to replicas until the first write executed with the returned context,
+// and to the primary afterwards.
+func WithStickyPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, &atomic.Bool{})
+}
+
+func usePrimary(ctx context.Context) bool {
+	flag, ok := ctx.Value(primaryKey{}).(*atomic.Bool)
+	return ok && flag.Load()
+}
+
+func markWritten(ctx context.Context) {
+	if flag, ok := ctx.Value(primaryKey{}).(*atomic.Bool); ok {
+		flag.Store(true)
+	}
+}
+
+// primaryOnly matches selects that must run on the primary: locking clauses, select into and calls of functions
+// with side effects or session state, such as sequences and advisory locks.
+var primaryOnly = regexp.MustCompile(
+	`\bfor\s+(update|no\s+key\s+update|share|key\s+share)\b|\binto\b|` +
+		`\b(nextval|setval|currval|lastval|pg_(try_)?advisory_\w+|pg_notify|set_config|txid_current|pg_current_xact_id)\s*\(`,
+)
+
+func isRead(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	return (strings.HasPrefix(query, "select") || strings.HasPrefix(query, "(select")) && !primaryOnly.MatchString(query)
+}
+
+// NewRoutingDb sends reads issued through an Executor to healthy replicas in turn; writes, locking reads,
+// volatile function calls and transactions go to the primary. Use WithPrimary for reads calling other functions
+// with side effects.
+func NewRoutingDb(primary Db, replicas ...Db) *routingDb {
+	return &routingDb{primary: primary, replicas: replicas}
+}
+
+type routingDb struct {
+	primary  Db
+	replicas []Db
+	next     atomic.Uint64
+}
+
+func (r *routingDb) Connect() *sqlx.DB {
+	return r.primary.Connect()
+}
+
+func (r *routingDb) Timeout() time.Duration {
+	return r.primary.Timeout()
+}
+
+func (r *routingDb) Read(ctx context.Context) *sqlx.DB {
+	if usePrimary(ctx) || len(r.replicas) == 0 {
+		return r.primary.Connect()
+	}
+	start := r.next.Add(1)
+	for i := 0; i < len(r.replicas); i++ {
+		replica := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if checker, ok := replica.(healthChecker); ok && !checker.Healthy() {
+			continue
+		}
+		return replica.Connect()
+	}
+	return r.primary.Connect()
+}
+
+func (r *routingDb) OnConnect(callback func(*sqlx.DB)) {
+	for _, notifier := range connectNotifiers(r) {
+		notifier.OnConnect(callback)
+	}
+}
+
+// connectNotifiers returns the notifiers of every connection behind the Db: the primary and the replicas of a
+// routing Db, or the Db itself.
+func connectNotifiers(connection Db) []connectNotifier {
+	if r, ok := connection.(*routingDb); ok {
+		var result []connectNotifier
+		for _, member := range append([]Db{r.primary}, r.replicas...) {
+			result = append(result, connectNotifiers(member)...)
+		}
+		return result
+	}
+	if notifier, ok := connection.(connectNotifier); ok {
+		return []connectNotifier{notifier}
+	}
+	return nil
+}
+
+func (r *routingDb) Close() error {
+	var result error
+	for _, connection := range append([]Db{r.primary}, r.replicas...) {
+		if closer, ok := connection.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil && result == nil {
+				result = err
+			}
+		}
+	}
+	return result
+}
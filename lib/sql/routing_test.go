@@ -0,0 +1,77 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type routingStub struct {
+	db      *sqlx.DB
+	healthy bool
+}
+
+func (s *routingStub) Connect() *sqlx.DB      { return s.db }
+func (s *routingStub) Timeout() time.Duration { return 0 }
+func (s *routingStub) Healthy() bool          { return s.healthy }
+
+func newRoutingStub(healthy bool) *routingStub {
+	return &routingStub{db: &sqlx.DB{}, healthy: healthy}
+}
+
+func TestRoutingDb(t *testing.T) {
+	t.Run(
+		"RoundRobin",
+		func(t *testing.T) {
+			primary, first, sick, second := newRoutingStub(true), newRoutingStub(true), newRoutingStub(false), newRoutingStub(true)
+			connection := NewRoutingDb(primary, first, sick, second)
+
+			seen := map[*sqlx.DB]int{}
+			for i := 0; i < 6; i++ {
+				seen[connection.Read(context.Background())]++
+			}
+			assert.Equal(t, 0, seen[sick.db])
+			assert.Equal(t, 0, seen[primary.db])
+			assert.Greater(t, seen[first.db], 0)
+			assert.Greater(t, seen[second.db], 0)
+			assert.Same(t, primary.db, connection.Connect())
+		},
+	)
+
+	t.Run(
+		"FallbackToPrimary",
+		func(t *testing.T) {
+			primary := newRoutingStub(true)
+			assert.Same(t, primary.db, NewRoutingDb(primary, newRoutingStub(false)).Read(context.Background()))
+			assert.Same(t, primary.db, NewRoutingDb(primary).Read(context.Background()))
+		},
+	)
+
+	t.Run(
+		"Executor",
+		func(t *testing.T) {
+			primary, replica := newRoutingStub(true), newRoutingStub(true)
+			e := NewExecutor(NewRoutingDb(primary, replica))
+
+			ctx := context.Background()
+			assert.Same(t, replica.db, e.target(ctx, "select * from t"))
+			assert.Same(t, primary.db, e.target(ctx, "select * from t for update"))
+			assert.Same(t, primary.db, e.target(ctx, "select * from t for no key update skip locked"))
+			assert.Same(t, primary.db, e.target(ctx, "select nextval('t_id_seq')"))
+			assert.Same(t, primary.db, e.target(ctx, "select pg_advisory_lock($1)"))
+			assert.Same(t, primary.db, e.target(ctx, "select pg_try_advisory_xact_lock ($1)"))
+			assert.Same(t, primary.db, e.target(ctx, "select * into t2 from t"))
+			assert.Same(t, replica.db, e.target(ctx, "select count(*), max(updated_at) from t where info = $1"))
+			assert.Same(t, primary.db, e.target(ctx, "insert into t (a) values ($1)"))
+			assert.Same(t, primary.db, e.target(WithPrimary(ctx), "select * from t"))
+
+			sticky := WithStickyPrimary(ctx)
+			assert.Same(t, replica.db, e.target(sticky, "select * from t"))
+			assert.Same(t, primary.db, e.target(sticky, "update t set a = $1"))
+			assert.Same(t, primary.db, e.target(sticky, "select * from t"))
+		},
+	)
+}
@@ -0,0 +1,176 @@
+package sqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+type connector struct {
+	db *Db
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{db: c.db}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("sqltest connections are created by sqltest.New")
+}
+
+type conn struct {
+	db *Db
+	tx bool
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.db.boundary("begin"); err != nil {
+		return nil, err
+	}
+	c.tx = true
+	return &tx{conn: c}, nil
+}
+
+// CheckNamedValue converts the arguments with the default driver conversion and rejects values it cannot
+// convert, as a real driver does.
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	value.Value = converted
+	return nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	script, err := c.db.execute(query, values(args), c.tx)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(script.affected), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	script, err := c.db.execute(query, values(args), c.tx)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: script.columns, rows: script.rows}, nil
+}
+
+func values(args []driver.NamedValue) []any {
+	result := make([]any, 0, len(args))
+	for _, arg := range args {
+		result = append(result, arg.Value)
+	}
+	return result
+}
+
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	t.conn.tx = false
+	_, err := t.conn.db.boundary("commit")
+	return err
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = false
+	_, err := t.conn.db.boundary("rollback")
+	return err
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func (s *stmt) CheckNamedValue(value *driver.NamedValue) error {
+	return s.conn.CheckNamedValue(value)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, 0, len(args))
+	for idx, arg := range args {
+		result = append(result, driver.NamedValue{Ordinal: idx + 1, Value: arg})
+	}
+	return result
+}
+
+type rows struct {
+	columns []string
+	rows    [][]any
+	current int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.current >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.current]
+	r.current++
+	if len(row) != len(dest) {
+		return fmt.Errorf("scripted row has %d values, expected %d", len(row), len(dest))
+	}
+	for idx, value := range row {
+		converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+		if err != nil {
+			return err
+		}
+		dest[idx] = converted
+	}
+	return nil
+}
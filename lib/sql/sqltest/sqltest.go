@@ -0,0 +1,209 @@
+// Package sqltest provides an in-memory sql.Db that records executed statements and transaction boundaries
+// and returns scripted results, so tests can assert what the code under test sends to the database.
+package sqltest
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+)
+
+const DriverName = "sqltest"
+
+var boundaryPattern = regexp.MustCompile(`^(?i)\s*(savepoint|release savepoint|rollback to savepoint)\s`)
+
+type Statement struct {
+	Query string
+	Args  []any
+	Tx    bool
+}
+
+type Script struct {
+	pattern  *regexp.Regexp
+	columns  []string
+	rows     [][]any
+	affected int64
+	err      error
+	once     bool
+	used     bool
+}
+
+// Rows makes matching queries return the rows.
+func (s *Script) Rows(columns []string, rows ...[]any) *Script {
+	s.columns = columns
+	s.rows = rows
+	return s
+}
+
+// Affected makes matching statements report the number of affected rows.
+func (s *Script) Affected(rows int64) *Script {
+	s.affected = rows
+	return s
+}
+
+// Error makes matching statements fail with the error.
+func (s *Script) Error(err error) *Script {
+	s.err = err
+	return s
+}
+
+// Once limits the script to the first matching statement.
+func (s *Script) Once() *Script {
+	s.once = true
+	return s
+}
+
+func New() *Db {
+	d := &Db{}
+	d.db = sqlx.NewDb(dbsql.OpenDB(connector{db: d}), DriverName)
+	return d
+}
+
+// Db implements sql.Db. Statements without a matching script return no rows and no affected rows.
+type Db struct {
+	mutex        sync.Mutex
+	db           *sqlx.DB
+	timeout      time.Duration
+	scripts      []*Script
+	statements   []Statement
+	transactions []string
+}
+
+func (d *Db) Connect() *sqlx.DB {
+	return d.db
+}
+
+func (d *Db) Timeout() time.Duration {
+	return d.timeout
+}
+
+func (d *Db) WithTimeout(timeout time.Duration) *Db {
+	d.timeout = timeout
+	return d
+}
+
+func (d *Db) Close() error {
+	return d.db.Close()
+}
+
+// On scripts the result of statements matching the regular expression. Scripts are tried in the order they were
+// added. The pattern is also matched against "begin", "commit" and "rollback" to script transaction failures.
+func (d *Db) On(pattern string) *Script {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	script := &Script{pattern: regexp.MustCompile(pattern)}
+	d.scripts = append(d.scripts, script)
+	return script
+}
+
+// Statements returns the executed statements, excluding transaction boundaries.
+func (d *Db) Statements() []Statement {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Statement{}, d.statements...)
+}
+
+func (d *Db) Queries() []string {
+	var result []string
+	for _, statement := range d.Statements() {
+		result = append(result, statement.Query)
+	}
+	return result
+}
+
+// Transactions returns the transaction boundaries: begin, commit, rollback and savepoint statements.
+func (d *Db) Transactions() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]string{}, d.transactions...)
+}
+
+// Reset forgets the recorded statements and boundaries but keeps the scripts.
+func (d *Db) Reset() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.statements = nil
+	d.transactions = nil
+}
+
+func (d *Db) AssertQueries(t testing.TB, expected ...string) bool {
+	t.Helper()
+	return assert.Equal(t, expected, d.Queries())
+}
+
+func (d *Db) AssertTransactions(t testing.TB, expected ...string) bool {
+	t.Helper()
+	return assert.Equal(t, expected, d.Transactions())
+}
+
+// AssertExecuted checks that a statement with the SQL and parameters of the builder has been executed. The builder
+// is built with the scopes of the context, like query.Exec does; query.Query adds "returning *" to modifying
+// builders, so set it on the expected builder as well.
+func (d *Db) AssertExecuted(t testing.TB, ctx context.Context, builder query.Builder) bool {
+	t.Helper()
+	sql, args := query.Scoped(ctx, builder).Build()
+	expected := Statement{Query: sql, Args: convert(*args)}
+	for _, statement := range d.Statements() {
+		if statement.Query == expected.Query && reflect.DeepEqual(statement.Args, expected.Args) {
+			return true
+		}
+	}
+	return assert.Fail(t, "builder has not been executed", "expected:\n%s\nexecuted:\n%s", query.Debug(query.Scoped(ctx, builder)), strings.Join(d.Queries(), "\n"))
+}
+
+// convert applies the driver conversion the recorded arguments went through.
+func convert(args []any) []any {
+	result := make([]any, 0, len(args))
+	for _, arg := range args {
+		if value, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			arg = value
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+func (d *Db) execute(statement string, args []any, tx bool) (*Script, error) {
+	if boundaryPattern.MatchString(statement) {
+		return d.boundary(strings.TrimSpace(statement))
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.statements = append(d.statements, Statement{Query: statement, Args: args, Tx: tx})
+	return d.match(statement)
+}
+
+func (d *Db) boundary(statement string) (*Script, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.transactions = append(d.transactions, statement)
+	return d.match(statement)
+}
+
+// match finds the script for the statement; the caller holds the mutex.
+func (d *Db) match(statement string) (*Script, error) {
+	for _, script := range d.scripts {
+		if script.once && script.used || !script.pattern.MatchString(statement) {
+			continue
+		}
+		script.used = true
+		return script, script.err
+	}
+	return &Script{}, nil
+}
+
+func (s Statement) String() string {
+	return fmt.Sprintf("%s %v", s.Query, s.Args)
+}
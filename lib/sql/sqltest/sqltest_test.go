@@ -0,0 +1,85 @@
+package sqltest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+)
+
+func TestDb(t *testing.T) {
+	t.Run(
+		"Scripts",
+		func(t *testing.T) {
+			db := New()
+			db.On(`^select id from users`).Rows([]string{"id"}, []any{1}, []any{2})
+			db.On(`^delete`).Affected(3).Once()
+			db.On(`^update`).Error(fmt.Errorf("failed"))
+			ctx := context.Background()
+
+			builder := query.NewBuilder("users").Select("id").Where(query.And("name", "eq", "a")).NotSort()
+			assert.Equal(t, []int{1, 2}, query.Query[[]int](ctx, db.Connect().SelectContext, builder))
+			assert.Equal(t, 3, query.Exec(ctx, db.Connect().ExecContext, query.NewBuilder("users").Delete()))
+			assert.Equal(t, 0, query.Exec(ctx, db.Connect().ExecContext, query.NewBuilder("users").Delete()))
+			assert.PanicsWithError(t, "failed", func() {
+				query.Exec(ctx, db.Connect().ExecContext, query.NewBuilder("users").Update(map[string]any{"a": 1}))
+			})
+
+			db.AssertQueries(t, "select id from users where (name = $1)", "delete from users", "delete from users", "update users set a=$1")
+			db.AssertExecuted(t, ctx, builder)
+			assert.Equal(t, Statement{Query: "update users set a=$1", Args: []any{int64(1)}}, db.Statements()[3])
+
+			db.Reset()
+			assert.Empty(t, db.Statements())
+		},
+	)
+
+	t.Run(
+		"Arguments",
+		func(t *testing.T) {
+			db := New()
+			_, err := db.Connect().ExecContext(context.Background(), "update users set a=$1", struct{}{})
+			assert.ErrorContains(t, err, "unsupported type struct {}")
+			assert.Empty(t, db.Statements())
+		},
+	)
+
+	t.Run(
+		"Scopes",
+		func(t *testing.T) {
+			query.SetScopes(query.SoftDelete("deleted_at", "users"))
+			defer query.SetScopes()
+			db := New()
+			ctx := context.Background()
+
+			query.Exec(ctx, db.Connect().ExecContext, query.NewBuilder("users").Delete().Where(query.And("id", "eq", 1)))
+			db.AssertQueries(t, "delete from users where (id = $1) and (deleted_at is null)")
+			db.AssertExecuted(t, ctx, query.NewBuilder("users").Delete().Where(query.And("id", "eq", 1)))
+		},
+	)
+
+	t.Run(
+		"Transactions",
+		func(t *testing.T) {
+			db := New()
+			db.On(`^commit$`).Error(fmt.Errorf("commit failed"))
+			ctx := context.Background()
+
+			tx, err := db.Connect().BeginTxx(ctx, nil)
+			assert.Nil(t, err)
+			_, err = tx.ExecContext(ctx, "savepoint level_1")
+			assert.Nil(t, err)
+			_, err = tx.ExecContext(ctx, "delete from users")
+			assert.Nil(t, err)
+			_, err = tx.ExecContext(ctx, "rollback to savepoint level_1")
+			assert.Nil(t, err)
+			assert.EqualError(t, tx.Commit(), "commit failed")
+
+			db.AssertTransactions(t, "begin", "savepoint level_1", "rollback to savepoint level_1", "commit")
+			assert.Equal(t, []Statement{{Query: "delete from users", Args: []any{}, Tx: true}}, db.Statements())
+		},
+	)
+}
@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type StatementStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+func (s StatementStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+type statement struct {
+	pool   *sqlx.DB
+	query  string
+	stmt   *sqlx.Stmt
+	users  int
+	closed bool
+}
+
+// statementCache keeps the most recently used prepared statements of every pool. Evicted statements are closed
+// once the last user releases them.
+type statementCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[*sqlx.DB]map[string]*list.Element
+	stats    StatementStats
+}
+
+func newStatementCache(capacity int) *statementCache {
+	return &statementCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[*sqlx.DB]map[string]*list.Element{},
+	}
+}
+
+func (c *statementCache) prepare(ctx context.Context, pool *sqlx.DB, query string) (*sqlx.Stmt, func(), error) {
+	if entry := c.acquire(pool, query); entry != nil {
+		return entry.stmt, c.releaser(entry), nil
+	}
+
+	stmt, err := pool.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.items[pool][query]; ok {
+		_ = stmt.Close()
+		entry := element.Value.(*statement)
+		entry.users++
+		c.order.MoveToFront(element)
+		return entry.stmt, c.releaser(entry), nil
+	}
+	entry := &statement{pool: pool, query: query, stmt: stmt, users: 1}
+	if c.items[pool] == nil {
+		c.items[pool] = map[string]*list.Element{}
+	}
+	c.items[pool][query] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		c.evict(c.order.Back())
+		c.stats.Evictions++
+	}
+	return stmt, c.releaser(entry), nil
+}
+
+func (c *statementCache) acquire(pool *sqlx.DB, query string) *statement {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.items[pool][query]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	entry := element.Value.(*statement)
+	entry.users++
+	return entry
+}
+
+func (c *statementCache) releaser(entry *statement) func() {
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		entry.users--
+		if entry.closed && entry.users == 0 {
+			_ = entry.stmt.Close()
+		}
+	}
+}
+
+// evict removes the element from the cache; the caller holds the mutex.
+func (c *statementCache) evict(element *list.Element) {
+	entry := c.order.Remove(element).(*statement)
+	delete(c.items[entry.pool], entry.query)
+	if len(c.items[entry.pool]) == 0 {
+		delete(c.items, entry.pool)
+	}
+	entry.closed = true
+	if entry.users == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// reconnected returns a connect callback for a single connection, which drops the statements of the pool that
+// connection used before. Statements of a pool opened before the callback was registered are not tracked and
+// leave the cache by eviction.
+func (c *statementCache) reconnected() func(*sqlx.DB) {
+	var mutex sync.Mutex
+	var previous *sqlx.DB
+	return func(pool *sqlx.DB) {
+		mutex.Lock()
+		stale := previous
+		previous = pool
+		mutex.Unlock()
+		if stale != nil && stale != pool {
+			c.purge(stale)
+		}
+	}
+}
+
+// purge drops the statements of the pool.
+func (c *statementCache) purge(pool *sqlx.DB) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, element := range c.items[pool] {
+		c.evict(element)
+	}
+}
+
+func (c *statementCache) Stats() StatementStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
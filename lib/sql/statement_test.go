@@ -0,0 +1,124 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	statementPrepares atomic.Int32
+	statementCloses   atomic.Int32
+)
+
+type statementDriver struct{}
+
+func (statementDriver) Open(string) (driver.Conn, error) { return statementConn{}, nil }
+
+type statementConn struct{}
+
+func (statementConn) Prepare(string) (driver.Stmt, error) {
+	statementPrepares.Add(1)
+	return statementStmt{}, nil
+}
+
+func (statementConn) Close() error              { return nil }
+func (statementConn) Begin() (driver.Tx, error) { return statementTx{}, nil }
+
+type statementTx struct{}
+
+func (statementTx) Commit() error   { return nil }
+func (statementTx) Rollback() error { return nil }
+
+type statementStmt struct{}
+
+func (statementStmt) Close() error {
+	statementCloses.Add(1)
+	return nil
+}
+
+func (statementStmt) NumInput() int                              { return -1 }
+func (statementStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (statementStmt) Query([]driver.Value) (driver.Rows, error)  { return statementRows{}, nil }
+
+type statementRows struct{}
+
+func (statementRows) Columns() []string         { return []string{"id"} }
+func (statementRows) Close() error              { return nil }
+func (statementRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	dbsql.Register("glb-stmt", statementDriver{})
+}
+
+func TestStatementCache(t *testing.T) {
+	connection := NewDb("glb-stmt", "", time.Second, PoolOptions{MaxOpen: 1})
+	defer func() { _ = connection.Close() }()
+	e := NewExecutor(connection).Cache(2)
+	ctx := context.Background()
+
+	statementPrepares.Store(0)
+	statementCloses.Store(0)
+	for _, query := range []string{"delete from a", "delete from a", "delete from b", "delete from c"} {
+		_, err := e.ExecContext(ctx, query)
+		assert.Nil(t, err)
+	}
+	var ids []int
+	assert.Nil(t, e.SelectContext(ctx, &ids, "select id from c"))
+
+	stats := e.StatementStats()
+	assert.Equal(t, StatementStats{Hits: 1, Misses: 4, Evictions: 2, Size: 2}, stats)
+	assert.Equal(t, 0.2, stats.HitRate())
+	assert.Equal(t, int32(4), statementPrepares.Load())
+	assert.Equal(t, int32(2), statementCloses.Load())
+
+	txCtx, _, commit, rollback := NewContextWithTransaction(ctx, connection)
+	defer rollback()
+	for i := 0; i < 3; i++ {
+		_, err := e.ExecContext(txCtx, "delete from c")
+		assert.Nil(t, err)
+	}
+	tx := ambientTransaction(txCtx)
+	assert.Len(t, tx.statements, 1)
+	assert.Equal(t, 1, e.statements.items[connection.Connect()]["delete from c"].Value.(*statement).users)
+	commit()
+	assert.Nil(t, tx.statements)
+	assert.Equal(t, 0, e.statements.items[connection.Connect()]["delete from c"].Value.(*statement).users)
+	assert.Equal(t, uint64(2), e.StatementStats().Hits)
+	assert.Equal(t, int32(4), statementPrepares.Load())
+}
+
+func TestStatementCacheReconnect(t *testing.T) {
+	first, second := sqlx.MustOpen("glb-stmt", ""), sqlx.MustOpen("glb-stmt", "")
+	defer func() { _ = first.Close() }()
+	defer func() { _ = second.Close() }()
+	cache := newStatementCache(10)
+	ctx := context.Background()
+	primary, replica := cache.reconnected(), cache.reconnected()
+
+	primary(first)
+	replica(second)
+	for _, pool := range []*sqlx.DB{first, second} {
+		_, release, err := cache.prepare(ctx, pool, "select id from a")
+		assert.Nil(t, err)
+		release()
+	}
+	assert.Equal(t, 2, cache.Stats().Size)
+
+	reconnected := sqlx.MustOpen("glb-stmt", "")
+	defer func() { _ = reconnected.Close() }()
+	replica(reconnected)
+	assert.Equal(t, 1, cache.Stats().Size)
+	assert.Contains(t, cache.items, first)
+	assert.NotContains(t, cache.items, second)
+
+	routing := NewRoutingDb(NewDb("glb-stmt", "", time.Second, PoolOptions{}), NewDb("glb-stmt", "", time.Second, PoolOptions{}))
+	assert.Len(t, connectNotifiers(routing), 2)
+}
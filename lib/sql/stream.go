@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/betam/glb/lib/sql/query"
+)
+
+const DefaultStreamBatch = 1000
+
+var streamSequence atomic.Uint64
+
+// Stream iterates over the builder result through a server-side cursor fetching batch rows at a time, so exports
+// of any size run in constant memory. The cursor lives in the ambient transaction or in a new one.
+// The callback follows the query.Each contract; returns the number of processed rows.
+func Stream[T any](ctx context.Context, connection Db, builder query.Builder, batch int, callback func(T) error) int {
+	if batch <= 0 {
+		batch = DefaultStreamBatch
+	}
+	ctx, tx, commit, rollback := NewContextWithTransaction(ctx, connection)
+	defer rollback()
+
+	exec := func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+		result, err := tx.ExecContext(ctx, query, args...)
+		return result, Classify(err)
+	}
+	fetch := func(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+		rows, err := tx.QueryxContext(ctx, query, args...)
+		return rows, Classify(err)
+	}
+	name := fmt.Sprintf("stream_%d", streamSequence.Add(1))
+	total := query.EachCursor(ctx, exec, fetch, name, builder, batch, callback)
+
+	commit()
+	return total
+}
@@ -0,0 +1,58 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+type streamHook struct {
+	events []query.Event
+}
+
+func (h *streamHook) Before(ctx context.Context, _ *query.Event) context.Context { return ctx }
+func (h *streamHook) After(_ context.Context, event *query.Event) {
+	h.events = append(h.events, *event)
+}
+
+func TestStream(t *testing.T) {
+	hook := &streamHook{}
+	query.SetHooks(hook)
+	defer query.SetHooks(query.LogHook{})
+
+	db := sqltest.New()
+	db.On(`^fetch forward 2`).Rows([]string{"id"}, []any{1}, []any{2}).Once()
+	db.On(`^fetch forward 2`).Rows([]string{"id"}, []any{3}).Once()
+
+	var ids []int
+	count := Stream(context.Background(), db, query.NewBuilder("users").Select("id").Where(query.And("active", "eq", true)), 2, func(id int) error {
+		ids = append(ids, id)
+		return nil
+	})
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	queries := db.Queries()
+	assert.Len(t, queries, 4)
+	assert.Regexp(t, `^declare stream_\d+ no scroll cursor for select id from users where \(active = \$1\) order by id asc$`, queries[0])
+	assert.Regexp(t, `^close stream_\d+$`, queries[3])
+	assert.Equal(t, []string{"begin", "commit"}, db.Transactions())
+
+	assert.Len(t, hook.events, 1)
+	assert.Equal(t, "select id from users where (active = $1) order by id asc", hook.events[0].Query)
+	assert.Equal(t, []any{true}, hook.events[0].Args)
+	assert.Equal(t, 3, hook.events[0].Rows)
+}
+
+func TestStreamScopes(t *testing.T) {
+	query.SetScopes(query.SoftDelete("deleted_at", "users"))
+	defer query.SetScopes()
+
+	db := sqltest.New()
+	Stream(context.Background(), db, query.NewBuilder("users").Select("id"), 2, func(id int) error { return nil })
+	assert.Regexp(t, `^declare stream_\d+ no scroll cursor for select id from users where \(deleted_at is null\) order by id asc$`, db.Queries()[0])
+}
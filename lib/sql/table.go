@@ -0,0 +1,110 @@
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/betam/glb/lib/list"
+	"github.com/betam/glb/lib/pointer"
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/try"
+)
+
+func NewTable[Entity any, ID any](connection Db, table string, key ...string) *Table[Entity, ID] {
+	t := &Table[Entity, ID]{DB: connection, executor: NewExecutor(connection), table: table, key: "id"}
+	if len(key) == 1 {
+		t.key = key[0]
+	} else if len(key) > 1 {
+		panic(fmt.Errorf("unexpected key count: expected 0 or 1 given %d", len(key)))
+	}
+	return t
+}
+
+type Table[Entity any, ID any] struct {
+	DB       Db
+	executor Executor
+	table    string
+	key      string
+}
+
+func (t *Table[Entity, ID]) Get(ctx context.Context, id ID) *Entity {
+	var result *Entity
+	try.Catch(
+		func() {
+			result = pointer.Pointer(query.Query[Entity](ctx, t.executor.GetContext, t.selectBuilder(query.And(t.key, "eq", id))))
+		},
+		func(throwable error) {
+			if !errors.Is(throwable, dbsql.ErrNoRows) {
+				panic(throwable)
+			}
+		},
+	)
+	return result
+}
+
+func (t *Table[Entity, ID]) Find(ctx context.Context, where query.Expression, sort []string, page int, count int) []Entity {
+	builder := t.selectBuilder(where).Page(page, count)
+	if len(sort) > 0 {
+		builder.Sort(sort...)
+	}
+	return query.Query[[]Entity](ctx, t.executor.SelectContext, builder)
+}
+
+func (t *Table[Entity, ID]) Count(ctx context.Context, where query.Expression) int {
+	builder := query.NewBuilder(t.table).Select("count(*)").NotSort()
+	if where != nil {
+		builder.Where(where)
+	}
+	return query.Query[int](ctx, t.executor.GetContext, builder)
+}
+
+func (t *Table[Entity, ID]) Exists(ctx context.Context, where query.Expression) bool {
+	builder := query.NewBuilder(t.table).Select("1").NotSort().Page(0, 1)
+	if where != nil {
+		builder.Where(where)
+	}
+	return len(query.Query[[]int](ctx, t.executor.SelectContext, builder)) > 0
+}
+
+func (t *Table[Entity, ID]) Insert(ctx context.Context, entity Entity) Entity {
+	return query.Query[Entity](ctx, t.executor.GetContext, query.NewBuilder(t.table).Insert().Values(&entity))
+}
+
+func (t *Table[Entity, ID]) Upsert(ctx context.Context, entity Entity, conflict ...string) Entity {
+	keys := []string{t.key}
+	if len(conflict) > 0 {
+		keys = conflict
+	}
+	fields, _ := query.Rows(nil, &entity)
+	// Readonly keys are skipped by the mapping, but the conflict never fires without them.
+	missing := list.Filter(keys, func(key string) bool { return !list.Contains(key, fields) })
+	fields = append(missing, fields...)
+	excluded := list.Filter(fields, func(field string) bool { return field != t.key && !list.Contains(field, keys) })
+	builder := query.NewBuilder(t.table).Insert(fields...).Values(&entity)
+	if len(excluded) > 0 {
+		builder.Conflict(strings.Join(keys, ", "), excluded...)
+	} else {
+		builder.Conflict(strings.Join(keys, ", "))
+	}
+	return query.Query[Entity](ctx, t.executor.GetContext, builder)
+}
+
+func (t *Table[Entity, ID]) Update(ctx context.Context, entity Entity) Entity {
+	builder := query.NewBuilder(t.table).UpdateStruct(&entity).Where(query.And(t.key, "eq", query.FieldValue(&entity, t.key)))
+	return query.Query[Entity](ctx, t.executor.GetContext, builder)
+}
+
+func (t *Table[Entity, ID]) Delete(ctx context.Context, id ID) int {
+	return query.Exec(ctx, t.executor.ExecContext, query.NewBuilder(t.table).Delete().Where(query.And(t.key, "eq", id)))
+}
+
+func (t *Table[Entity, ID]) selectBuilder(where query.Expression) query.Builder {
+	builder := query.NewBuilder(t.table).Select().Sort(t.key + " asc")
+	if where != nil {
+		builder.Where(where)
+	}
+	return builder
+}
@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/query"
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+type tableUser struct {
+	Id   int    `db:"id,readonly"`
+	Name string `db:"name"`
+}
+
+func TestTable(t *testing.T) {
+	t.Run(
+		"Crud",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On(`^select \* from users where \(id = \$1\)`).Rows([]string{"id", "name"}, []any{1, "a"}).Once()
+			db.On(`^select \* from users where \(name like \$1\)`).Rows([]string{"id", "name"}, []any{1, "a"}, []any{3, "ab"})
+			db.On(`^select count`).Rows([]string{"count"}, []any{5})
+			db.On(`^select 1`).Rows([]string{"?column?"}, []any{1})
+			db.On(`^insert|^update`).Rows([]string{"id", "name"}, []any{2, "b"})
+			db.On(`^delete`).Affected(1)
+			users := NewTable[tableUser, int](db, "users")
+			ctx := context.Background()
+
+			assert.Equal(t, &tableUser{1, "a"}, users.Get(ctx, 1))
+			assert.Nil(t, users.Get(ctx, 1))
+			assert.Equal(t, []tableUser{{1, "a"}, {3, "ab"}}, users.Find(ctx, query.And("name", "like", "a%"), []string{"name desc"}, 1, 2))
+			assert.Equal(t, 5, users.Count(ctx, query.And("name", "like", "a%")))
+			assert.True(t, users.Exists(ctx, nil))
+			assert.Equal(t, tableUser{2, "b"}, users.Insert(ctx, tableUser{Name: "b"}))
+			assert.Equal(t, tableUser{2, "b"}, users.Upsert(ctx, tableUser{Id: 2, Name: "b"}))
+			assert.Equal(t, tableUser{2, "b"}, users.Update(ctx, tableUser{Id: 2, Name: "b"}))
+			assert.Equal(t, 1, users.Delete(ctx, 2))
+
+			assert.Equal(
+				t,
+				[]string{
+					"select * from users where (id = $1) order by id asc",
+					"select * from users where (id = $1) order by id asc",
+					"select * from users where (name like $1) order by name desc offset 2 limit 2",
+					"select count(*) from users where (name like $1)",
+					"select 1 from users limit 1",
+					"insert into users (name) values ($1) returning *",
+					"insert into users (id, name) values ($1, $2) on conflict (id) do update set name=excluded.name returning *",
+					"update users set name=$1 where (id = $2) returning *",
+					"delete from users where (id = $1)",
+				},
+				db.Queries(),
+			)
+			assert.Equal(t, []any{int64(2), "b"}, db.Statements()[6].Args)
+			assert.Equal(t, []any{"b", int64(2)}, db.Statements()[7].Args)
+		},
+	)
+
+	t.Run(
+		"Transaction",
+		func(t *testing.T) {
+			db := sqltest.New()
+			users := NewTable[tableUser, int](db, "users")
+
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			defer rollback()
+			users.Delete(ctx, 1)
+			commit()
+			users.Delete(context.Background(), 2)
+
+			statements := db.Statements()
+			assert.True(t, statements[0].Tx)
+			assert.False(t, statements[1].Tx)
+			assert.Equal(t, []string{"begin", "commit"}, db.Transactions())
+		},
+	)
+
+	t.Run(
+		"WrongArguments",
+		func(t *testing.T) {
+			assert.PanicsWithError(t, "unexpected key count: expected 0 or 1 given 2", func() { NewTable[tableUser, int](nil, "users", "a", "b") })
+		},
+	)
+}
@@ -2,53 +2,152 @@ package sql
 
 import (
 	"context"
+	dbsql "database/sql"
 	"fmt"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
 
 	"github.com/betam/glb/lib/try"
 )
 
 type transactionKey struct{}
 
+func TransactionFromContext(ctx context.Context) *sqlx.Tx {
+	if tx := ambientTransaction(ctx); tx != nil {
+		return tx.Tx
+	}
+	return nil
+}
+
+func ambientTransaction(ctx context.Context) *transaction {
+	if tx, ok := ctx.Value(transactionKey{}).(*transaction); ok && !tx.isDone {
+		return tx
+	}
+	return nil
+}
+
 type transaction struct {
 	*sqlx.Tx
-	level  int
-	isDone bool
+	db            *sqlx.DB
+	level         int
+	savepoints    int
+	isDone        bool
+	afterCommit   []func()
+	afterRollback []func()
+	mutex         sync.Mutex
+	statements    map[string]*sqlx.Stmt
+	releases      []func()
+}
+
+// statement returns the cached statement bound to the transaction, binding the one returned by prepare on the
+// first use of the query. Prepared statements are released when the transaction ends.
+func (t *transaction) statement(ctx context.Context, query string, prepare func() (*sqlx.Stmt, func(), error)) (*sqlx.Stmt, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if stmt, ok := t.statements[query]; ok {
+		return stmt, nil
+	}
+	stmt, release, err := prepare()
+	if err != nil {
+		return nil, err
+	}
+	if t.statements == nil {
+		t.statements = map[string]*sqlx.Stmt{}
+	}
+	t.statements[query] = t.StmtxContext(ctx, stmt)
+	t.releases = append(t.releases, release)
+	return t.statements[query], nil
+}
+
+func (t *transaction) release() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, release := range t.releases {
+		release()
+	}
+	t.statements, t.releases = nil, nil
+}
+
+func AfterCommit(ctx context.Context, hook func()) {
+	if tx, ok := ctx.Value(transactionKey{}).(*transaction); ok && !tx.isDone {
+		tx.afterCommit = append(tx.afterCommit, hook)
+		return
+	}
+	runHooks(ctx, []func(){hook})
+}
+
+func AfterRollback(ctx context.Context, hook func()) {
+	if tx, ok := ctx.Value(transactionKey{}).(*transaction); ok && !tx.isDone {
+		tx.afterRollback = append(tx.afterRollback, hook)
+	}
+}
+
+func runHooks(ctx context.Context, hooks []func()) {
+	for _, hook := range hooks {
+		try.Catch(hook, func(throwable error) {
+			logrus.WithContext(ctx).Warnf("transaction hook failed: %v", throwable)
+		})
+	}
 }
 
 func NewContextWithTransaction(ctx context.Context, connection Db) (childCtx context.Context, tx *sqlx.Tx, commit func(), rollback func()) {
+	return NewContextWithTransactionOptions(ctx, connection, nil)
+}
+
+func NewContextWithTransactionOptions(ctx context.Context, connection Db, opts *dbsql.TxOptions) (childCtx context.Context, tx *sqlx.Tx, commit func(), rollback func()) {
 	if existsTx, ok := ctx.Value(transactionKey{}).(*transaction); ok && !existsTx.isDone {
 		childCtx = ctx
+		// Savepoints are numbered per transaction rather than by depth, so sibling savepoints never share a name.
 		existsTx.level++
+		existsTx.savepoints++
 		isDone := false
-		savepoint := fmt.Sprintf("level_%d", existsTx.level)
+		savepoint := fmt.Sprintf("sp_%d", existsTx.savepoints)
+		commitHooks, rollbackHooks := len(existsTx.afterCommit), len(existsTx.afterRollback)
 		_ = try.Throw(existsTx.ExecContext(ctx, fmt.Sprintf("savepoint %s", savepoint)))
 		commit = func() {
 			if !isDone {
 				_ = try.Throw(existsTx.ExecContext(ctx, fmt.Sprintf("release savepoint %s", savepoint)))
+				existsTx.level--
 			}
 			isDone = true
 		}
 		rollback = func() {
 			if !isDone {
+				isDone = true
 				_ = try.Throw(existsTx.ExecContext(ctx, fmt.Sprintf("rollback to savepoint %s", savepoint)))
+				existsTx.level--
+				hooks := existsTx.afterRollback[rollbackHooks:]
+				existsTx.afterCommit = existsTx.afterCommit[:commitHooks]
+				existsTx.afterRollback = existsTx.afterRollback[:rollbackHooks]
+				runHooks(ctx, hooks)
 			}
-			isDone = true
 		}
 		tx = existsTx.Tx
 	} else {
-		existsTx = &transaction{Tx: try.Throw(connection.Connect().BeginTxx(ctx, nil))}
+		db := connection.Connect()
+		existsTx = &transaction{Tx: try.Throw(db.BeginTxx(ctx, opts)), db: db}
 		childCtx = context.WithValue(ctx, transactionKey{}, existsTx)
 		commit = func() {
-			try.ThrowError(existsTx.Tx.Commit())
+			// A failed commit ends the transaction as well, so the deferred rollback must not run and hide the error.
+			err := existsTx.Tx.Commit()
 			existsTx.isDone = true
+			existsTx.release()
+			if err != nil {
+				runHooks(ctx, existsTx.afterRollback)
+				try.ThrowError(err)
+			}
+			runHooks(ctx, existsTx.afterCommit)
 		}
 		rollback = func() {
 			if !existsTx.isDone {
-				try.ThrowError(existsTx.Tx.Rollback())
+				existsTx.isDone = true
+				err := existsTx.Tx.Rollback()
+				existsTx.release()
+				runHooks(ctx, existsTx.afterRollback)
+				try.ThrowError(err)
 			}
-			existsTx.isDone = true
 		}
 		tx = existsTx.Tx
 	}
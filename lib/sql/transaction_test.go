@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/betam/glb/lib/sql/sqltest"
+)
+
+func TestTransaction(t *testing.T) {
+	t.Run(
+		"Savepoints",
+		func(t *testing.T) {
+			db := sqltest.New()
+
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			defer rollback()
+			first, _, firstCommit, _ := NewContextWithTransaction(ctx, db)
+			_, _, _, nestedRollback := NewContextWithTransaction(first, db)
+			nestedRollback()
+			firstCommit()
+			_, _, secondCommit, _ := NewContextWithTransaction(ctx, db)
+			secondCommit()
+			commit()
+
+			assert.Equal(
+				t,
+				[]string{"begin", "savepoint sp_1", "savepoint sp_2", "rollback to savepoint sp_2", "release savepoint sp_1", "savepoint sp_3", "release savepoint sp_3", "commit"},
+				db.Transactions(),
+			)
+		},
+	)
+
+	t.Run(
+		"CommitFailure",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On("^commit$").Error(fmt.Errorf("commit failed"))
+
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			assert.PanicsWithError(t, "commit failed", commit)
+			assert.NotPanics(t, rollback)
+			assert.Nil(t, TransactionFromContext(ctx))
+			assert.Equal(t, []string{"begin", "commit"}, db.Transactions())
+		},
+	)
+
+	t.Run(
+		"Hooks",
+		func(t *testing.T) {
+			db := sqltest.New()
+			var events []string
+
+			AfterCommit(context.Background(), func() { events = append(events, "immediately") })
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			defer rollback()
+			AfterCommit(ctx, func() { events = append(events, "committed") })
+			AfterCommit(ctx, func() { panic(fmt.Errorf("ignored")) })
+
+			nested, _, _, nestedRollback := NewContextWithTransaction(ctx, db)
+			AfterCommit(nested, func() { events = append(events, "nested committed") })
+			AfterRollback(nested, func() { events = append(events, "nested rolled back") })
+			nestedRollback()
+			assert.Equal(t, []string{"immediately", "nested rolled back"}, events)
+
+			commit()
+			assert.Equal(t, []string{"immediately", "nested rolled back", "committed"}, events)
+		},
+	)
+
+	t.Run(
+		"RollbackHooks",
+		func(t *testing.T) {
+			db := sqltest.New()
+			db.On("^commit$").Error(fmt.Errorf("commit failed"))
+			var events []string
+
+			ctx, _, commit, rollback := NewContextWithTransaction(context.Background(), db)
+			AfterCommit(ctx, func() { events = append(events, "committed") })
+			AfterRollback(ctx, func() { events = append(events, "rolled back") })
+			assert.PanicsWithError(t, "commit failed", commit)
+			rollback()
+			assert.Equal(t, []string{"rolled back"}, events)
+
+			ctx, _, _, rollback = NewContextWithTransaction(context.Background(), db)
+			AfterRollback(ctx, func() { events = append(events, "rolled back again") })
+			rollback()
+			assert.Equal(t, []string{"rolled back", "rolled back again"}, events)
+		},
+	)
+}
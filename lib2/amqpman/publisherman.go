@@ -2,12 +2,13 @@ package amqpman
 
 import (
 	"context"
+	"fmt"
 	"github.com/betam/glb/lib2"
 	amqp "github.com/rabbitmq/amqp091-go"
 	log "github.com/sirupsen/logrus"
 )
 
-func Publish(publisherCh *amqp.Channel, context context.Context, exchange string, routingKey string, payload []byte) {
+func Publish(publisherCh *amqp.Channel, context context.Context, exchange string, routingKey string, payload []byte) error {
 
 	err := publisherCh.PublishWithContext(
 		context,
@@ -22,5 +23,33 @@ func Publish(publisherCh *amqp.Channel, context context.Context, exchange string
 	)
 	lib2.LogOnError(err, "Failed to publish a message", "warn")
 	log.Tracef("[R>] <%v>", routingKey)
+	return err
+}
+
+// PublishConfirmed publishes on a channel in confirm mode and waits until the broker acknowledges the message.
+func PublishConfirmed(publisherCh *amqp.Channel, context context.Context, exchange string, routingKey string, payload []byte) error {
 
+	confirmation, err := publisherCh.PublishWithDeferredConfirmWithContext(
+		context,
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        payload,
+		},
+	)
+	if err == nil && confirmation == nil {
+		err = fmt.Errorf("channel is not in confirm mode")
+	}
+	if err == nil {
+		var acked bool
+		if acked, err = confirmation.WaitContext(context); err == nil && !acked {
+			err = fmt.Errorf("message is not acknowledged by the broker")
+		}
+	}
+	lib2.LogOnError(err, "Failed to publish a message", "warn")
+	log.Tracef("[R>] <%v>", routingKey)
+	return err
 }